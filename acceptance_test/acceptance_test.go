@@ -17,6 +17,7 @@ package acceptancetest
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"os"
 	"testing"
 	"time"
@@ -33,6 +34,7 @@ import (
 )
 
 //go:generate depot generate-repo --table=messages --out ./messagerepo_gen.go $GOFILE Message
+//go:generate depot generate-repo --table=tags --out ./tagrepo_gen.go $GOFILE Tag
 
 type (
 	// Message demonstrates a persistent struct showing several mapped fields.
@@ -45,6 +47,11 @@ type (
 		Created    time.Time  `depot:"created"`
 		Updated    *time.Time `depot:"updated,nullable"`
 	}
+
+	// Tag is a second persistent struct used to check that repositories return distinct errors.
+	Tag struct {
+		Name string `depot:"name,id"`
+	}
 )
 
 func TestMariaDB(t *testing.T) {
@@ -139,6 +146,22 @@ create table messages (
 	})
 }
 
+func TestRepoErrors(t *testing.T) {
+	messageErrs := []error{ErrMessageNotFound, ErrMessageDuplicate, ErrMessageForeignKeyViolation, ErrMessageNotNullViolation, ErrMessageCheckViolation, ErrMessageDeadlock}
+	tagErrs := []error{ErrTagNotFound, ErrTagDuplicate, ErrTagForeignKeyViolation, ErrTagNotNullViolation, ErrTagCheckViolation, ErrTagDeadlock}
+	depotErrs := []error{depot.ErrNoResult, depot.ErrUniqueViolation, depot.ErrForeignKeyViolation, depot.ErrNotNullViolation, depot.ErrCheckViolation, depot.ErrDeadlock}
+
+	for i := range messageErrs {
+		if messageErrs[i] == tagErrs[i] || errors.Is(messageErrs[i], tagErrs[i]) || errors.Is(tagErrs[i], messageErrs[i]) {
+			t.Errorf("expected %v and %v to be distinct", messageErrs[i], tagErrs[i])
+		}
+
+		if !errors.Is(messageErrs[i], depotErrs[i]) || !errors.Is(tagErrs[i], depotErrs[i]) {
+			t.Errorf("expected %v and %v to match %v", messageErrs[i], tagErrs[i], depotErrs[i])
+		}
+	}
+}
+
 func runTest(t *testing.T, pool *sql.DB, opts depot.Options) {
 	db := depot.New(pool, opts)
 	defer db.Close()
@@ -179,6 +202,26 @@ func runTest(t *testing.T, pool *sql.DB, opts depot.Options) {
 		t.Errorf("unexpected value when loading after insert: %s", diff)
 	}
 
+	// Use a nested transaction as some databases abort the transaction on constraint violations.
+	nestedCtx, err := repo.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Insert(nestedCtx, &want); !errors.Is(err, ErrMessageDuplicate) {
+		t.Errorf("expected duplicate error when inserting twice but got %v", err)
+	}
+	if err := repo.Rollback(nestedCtx); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := repo.FindAll(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 {
+		t.Errorf("expected 1 message when finding all but got %d", len(all))
+	}
+
 	want.Text = "hello, one more time"
 	var updated = time.Now().UTC().Round(time.Second)
 	want.Updated = &updated
@@ -187,6 +230,16 @@ func runTest(t *testing.T, pool *sql.DB, opts depot.Options) {
 		t.Fatal(err)
 	}
 
+	if err := repo.Update(ctx, &want); err != nil {
+		t.Errorf("expected updating an unchanged message to succeed but got %v", err)
+	}
+
+	missing := want
+	missing.ID = "missing"
+	if err := repo.Update(ctx, &missing); !errors.Is(err, ErrMessageNotFound) {
+		t.Errorf("expected no result error when updating a missing message but got %v", err)
+	}
+
 	got, err = repo.LoadByID(ctx, "1")
 	if err != nil {
 		t.Fatal(err)
@@ -196,8 +249,83 @@ func runTest(t *testing.T, pool *sql.DB, opts depot.Options) {
 		t.Errorf("unexpected value when loading after update: %s", diff)
 	}
 
-	err = repo.DeleteByID(ctx, "1")
+	want.Text = "hello, saved"
+
+	if err := repo.Save(ctx, &want); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = repo.LoadByID(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := deep.Equal(want, *got); diff != nil {
+		t.Errorf("unexpected value when loading after save: %s", diff)
+	}
+
+	err = repo.InsertAll(ctx, []*Message{
+		{ID: "2", Text: "two", Attachment: []byte{}, Created: want.Created},
+		{ID: "3", Text: "three", Attachment: []byte{}, Created: want.Created},
+	})
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+
+	all, err = repo.FindAll(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Errorf("expected 3 messages after inserting all but got %d", len(all))
+	}
+
+	var ids []string
+	err = repo.findEach(ctx, func(m *Message) error {
+		ids = append(ids, m.ID)
+		return nil
+	}, depot.OrderBy(depot.Asc("id")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(ids, []string{"1", "2", "3"}); diff != nil {
+		t.Errorf("unexpected messages when streaming: %s", diff)
+	}
+
+	errStop := errors.New("stop")
+	ids = nil
+	err = repo.findEach(ctx, func(m *Message) error {
+		ids = append(ids, m.ID)
+		return errStop
+	}, depot.OrderBy(depot.Asc("id")))
+	if !errors.Is(err, errStop) || len(ids) != 1 {
+		t.Errorf("expected streaming to stop with the callback's error but got %v after %v", err, ids)
+	}
+
+	// The cursor has been closed once findEach returns, so the transaction can be used again.
+	if count, err := repo.count(ctx); err != nil || count != 3 {
+		t.Errorf("expected to count 3 messages after streaming but got %d, %v", count, err)
+	}
+
+	for _, id := range []string{"1", "2", "3"} {
+		if err := repo.DeleteByID(ctx, id); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if err := repo.DeleteByID(ctx, "1"); !errors.Is(err, ErrMessageNotFound) || !errors.Is(err, depot.ErrNoResult) || errors.Is(err, ErrTagNotFound) {
+		t.Errorf("expected no result error when deleting a missing message but got %v", err)
+	}
+
+	page, next, err := repo.FindPage(ctx, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 0 || next != "" {
+		t.Errorf("expected empty page without cursor but got %d messages and cursor %q", len(page), next)
+	}
+
+	if _, _, err := repo.FindPage(ctx, "", 0); err == nil {
+		t.Error("expected error when finding a page of size 0")
 	}
 }
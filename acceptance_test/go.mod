@@ -3,10 +3,10 @@ module acceptancetest
 go 1.16
 
 require (
-	github.com/go-sql-driver/mysql v1.6.0 // indirect
-	github.com/go-test/deep v1.0.7 // indirect
+	github.com/go-sql-driver/mysql v1.6.0
+	github.com/go-test/deep v1.0.7
 	github.com/halimath/depot v0.0.0
-	github.com/lib/pq v1.10.3 // indirect
+	github.com/lib/pq v1.10.3
 	github.com/mattn/go-sqlite3 v1.14.6
 )
 
@@ -0,0 +1,311 @@
+// This file has been generated by github.com/halimath/depot.
+// Any changes will be overwritten when re-generating.
+
+package acceptancetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/halimath/depot"
+)
+
+var (
+	tagRepoCols  = depot.Cols("name")
+	tagRepoTable = depot.Table("tags")
+)
+
+// Errors returned from TagRepo. Use errors.Is to check for them. Each error also matches the
+// depot error it is based on.
+var (
+	// ErrTagNotFound is returned when no Tag matches the query.
+	ErrTagNotFound = &tagRepoError{msg: "tag not found", err: depot.ErrNoResult}
+
+	// ErrTagDuplicate is returned when storing a Tag violates a unique constraint.
+	ErrTagDuplicate = &tagRepoError{msg: "duplicate tag", err: depot.ErrUniqueViolation}
+
+	// ErrTagForeignKeyViolation is returned when storing a Tag violates a foreign key constraint.
+	ErrTagForeignKeyViolation = &tagRepoError{msg: "tag violates a foreign key constraint", err: depot.ErrForeignKeyViolation}
+
+	// ErrTagNotNullViolation is returned when storing a Tag with null in a not null column.
+	ErrTagNotNullViolation = &tagRepoError{msg: "tag violates a not null constraint", err: depot.ErrNotNullViolation}
+
+	// ErrTagCheckViolation is returned when storing a Tag violates a check constraint.
+	ErrTagCheckViolation = &tagRepoError{msg: "tag violates a check constraint", err: depot.ErrCheckViolation}
+
+	// ErrTagDeadlock is returned when the database aborted a statement accessing Tag to resolve a deadlock.
+	ErrTagDeadlock = &tagRepoError{msg: "deadlock accessing tag", err: depot.ErrDeadlock}
+)
+
+// tagRepoErrors lists the errors returned from TagRepo.
+var tagRepoErrors = []*tagRepoError{
+	ErrTagNotFound,
+	ErrTagDuplicate,
+	ErrTagForeignKeyViolation,
+	ErrTagNotNullViolation,
+	ErrTagCheckViolation,
+	ErrTagDeadlock,
+}
+
+// tagRepoError is the type of the errors returned from TagRepo. The exported
+// errors unwrap to the depot error they are based on. Errors returned from the methods match the exported
+// error of their kind using errors.Is and unwrap to the error reported by depot.
+type tagRepoError struct {
+	kind *tagRepoError
+	msg  string
+	err  error
+}
+
+func (e *tagRepoError) Error() string {
+	return e.msg
+}
+
+func (e *tagRepoError) Is(target error) bool {
+	return e.kind != nil && target == error(e.kind)
+}
+
+func (e *tagRepoError) Unwrap() error {
+	return e.err
+}
+
+type TagRepo struct {
+	db *depot.DB
+}
+
+func (r *TagRepo) Begin(ctx context.Context) (context.Context, error) {
+	_, ctx, err := r.db.BeginTx(ctx)
+	return ctx, err
+}
+
+func (r *TagRepo) Commit(ctx context.Context) error {
+	tx := depot.MustGetTx(ctx)
+	return r.wrapError(tx.Commit())
+}
+
+func (r *TagRepo) Rollback(ctx context.Context) error {
+	tx := depot.MustGetTx(ctx)
+	return tx.Rollback()
+}
+
+// wrapError wraps err so that it matches the error returned from TagRepo that corresponds to the
+// depot error contained in err. Other errors are returned unchanged.
+func (r *TagRepo) wrapError(err error) error {
+	for _, kind := range tagRepoErrors {
+		if errors.Is(err, kind.err) {
+			return &tagRepoError{kind: kind, msg: err.Error(), err: err}
+		}
+	}
+	return err
+}
+
+func (r *TagRepo) fromValues(vals depot.Values) (*Tag, error) {
+	var ok bool
+
+	var name string
+
+	name, ok = vals.GetString("name")
+
+	if !ok {
+		return nil, fmt.Errorf("failed to get name for Tag: invalid value: %#v", vals["name"])
+	}
+
+	return &Tag{
+		Name: name,
+	}, nil
+}
+
+func (r *TagRepo) find(ctx context.Context, clauses ...depot.SelectClause) ([]*Tag, error) {
+	tx := depot.MustGetTx(ctx)
+	vals, err := tx.QueryMany(tagRepoCols, tagRepoTable, clauses...)
+	if err != nil {
+		err = r.wrapError(fmt.Errorf("failed to load Tag: %w", err))
+		tx.Error(err)
+		return nil, err
+	}
+
+	res := make([]*Tag, 0, len(vals))
+	for _, v := range vals {
+		entity, err := r.fromValues(v)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, entity)
+	}
+	return res, nil
+}
+
+// findEach streams the Tags matching clauses to fn one at a time. The rows are read while fn
+// is called, so fn must not use the transaction bound to ctx, i.e. it must not call other methods of the
+// repository. Use find to load all entities first if fn needs to access the database.
+func (r *TagRepo) findEach(ctx context.Context, fn func(*Tag) error, clauses ...depot.SelectClause) error {
+	tx := depot.MustGetTx(ctx)
+	cursor, err := tx.QueryIter(tagRepoCols, tagRepoTable, clauses...)
+	if err != nil {
+		err = r.wrapError(fmt.Errorf("failed to load Tag: %w", err))
+		tx.Error(err)
+		return err
+	}
+	defer cursor.Close()
+
+	for cursor.Next() {
+		entity, err := r.fromValues(cursor.Values())
+		if err != nil {
+			return err
+		}
+		if err := fn(entity); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		err = r.wrapError(fmt.Errorf("failed to load Tag: %w", err))
+		tx.Error(err)
+		return err
+	}
+	return nil
+}
+
+func (r *TagRepo) count(ctx context.Context, clauses ...depot.WhereClause) (int, error) {
+	tx := depot.MustGetTx(ctx)
+	count, err := tx.QueryCount(tagRepoTable, clauses...)
+	if err != nil {
+		err = r.wrapError(fmt.Errorf("failed to count Tag: %w", err))
+		tx.Error(err)
+		return 0, err
+	}
+
+	return count, err
+}
+
+func (r *TagRepo) FindAll(ctx context.Context, offset, limit int) ([]*Tag, error) {
+	clauses := []depot.SelectClause{depot.OrderBy(depot.Asc("name"))}
+	if offset > 0 {
+		clauses = append(clauses, depot.Offset(offset))
+	}
+	if limit > 0 {
+		clauses = append(clauses, depot.Limit(limit))
+	}
+	return r.find(ctx, clauses...)
+}
+
+func (r *TagRepo) LoadByName(ctx context.Context, Name string) (*Tag, error) {
+	tx := depot.MustGetTx(ctx)
+	vals, err := tx.QueryOne(tagRepoCols, tagRepoTable, depot.Where(depot.Eq("name", Name)))
+	if err != nil {
+		err = r.wrapError(fmt.Errorf("failed to load Tag by Name: %w", err))
+		if !errors.Is(err, depot.ErrNoResult) {
+			tx.Error(err)
+		}
+		return nil, err
+	}
+	return r.fromValues(vals)
+}
+
+func (r *TagRepo) FindPage(ctx context.Context, cursor string, size int) ([]*Tag, string, error) {
+	if size <= 0 {
+		return nil, "", fmt.Errorf("failed to load Tag page: invalid page size %d", size)
+	}
+
+	orderBy := depot.OrderBy(depot.Asc("name"))
+	clauses := []depot.SelectClause{orderBy, depot.Limit(size)}
+	if cursor != "" {
+		after, err := depot.SeekAfterToken(orderBy, cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to load Tag page: %w", err)
+		}
+		clauses = append(clauses, depot.Where(after))
+	}
+
+	entities, err := r.find(ctx, clauses...)
+	if err != nil || len(entities) == 0 || len(entities) < size {
+		return entities, "", err
+	}
+
+	next, err := depot.KeysetToken(entities[len(entities)-1].Name)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load Tag page: %w", err)
+	}
+	return entities, next, nil
+}
+
+func (r *TagRepo) toValues(entity *Tag) depot.Values {
+	return depot.Values{
+		"name": entity.Name,
+	}
+}
+
+func (r *TagRepo) Insert(ctx context.Context, entity *Tag) error {
+	tx := depot.MustGetTx(ctx)
+	err := tx.InsertOne(tagRepoTable, r.toValues(entity))
+	if err != nil {
+		err = r.wrapError(fmt.Errorf("failed to insert Tag: %w", err))
+	}
+	return err
+}
+
+func (r *TagRepo) InsertAll(ctx context.Context, entities []*Tag) error {
+	tx := depot.MustGetTx(ctx)
+	rows := make([]depot.Values, len(entities))
+	for i, entity := range entities {
+		rows[i] = r.toValues(entity)
+	}
+
+	err := tx.InsertMany(tagRepoTable, rows)
+	if err != nil {
+		err = r.wrapError(fmt.Errorf("failed to insert Tags: %w", err))
+	}
+	return err
+}
+
+func (r *TagRepo) delete(ctx context.Context, clauses ...depot.WhereClause) (int64, error) {
+	tx := depot.MustGetTx(ctx)
+	n, err := tx.DeleteMany(tagRepoTable, clauses...)
+	if err != nil {
+		err = r.wrapError(fmt.Errorf("failed to delete Tag: %w", err))
+	}
+	return n, err
+}
+
+func (r *TagRepo) Update(ctx context.Context, entity *Tag) error {
+	tx := depot.MustGetTx(ctx)
+	n, err := tx.UpdateMany(tagRepoTable, r.toValues(entity), depot.Where(depot.Eq("name", entity.Name)))
+	if err != nil {
+		return r.wrapError(fmt.Errorf("failed to update Tag: %w", err))
+	}
+	if n == 0 {
+		// MySQL reports unchanged rows as not affected, so check whether the row exists.
+		count, err := r.count(ctx, depot.Where(depot.Eq("name", entity.Name)))
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return r.wrapError(fmt.Errorf("failed to update Tag: %w", depot.ErrNoResult))
+		}
+	}
+	return nil
+}
+
+func (r *TagRepo) Save(ctx context.Context, entity *Tag) error {
+	tx := depot.MustGetTx(ctx)
+	err := tx.Upsert(tagRepoTable, r.toValues(entity), depot.Cols("name"), nil)
+	if err != nil {
+		err = r.wrapError(fmt.Errorf("failed to save Tag: %w", err))
+	}
+	return err
+}
+
+func (r *TagRepo) DeleteByName(ctx context.Context, Name string) error {
+	n, err := r.delete(ctx, depot.Where(depot.Eq("name", Name)))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return r.wrapError(fmt.Errorf("failed to delete Tag: %w", depot.ErrNoResult))
+	}
+	return nil
+}
+
+func (r *TagRepo) Delete(ctx context.Context, entity *Tag) error {
+	return r.DeleteByName(ctx, entity.Name)
+}
@@ -14,6 +14,8 @@
 
 package depot
 
+import "strings"
+
 // ClauseWriter defines the interface for types that can be used to generate SQL and bound variables for a
 // query.
 type ClauseWriter interface {
@@ -26,6 +28,10 @@ type ClauseWriter interface {
 	// BindParameter binds a new parameter. This method adds a parameter placeholder to the current query
 	// and adds arg as the bound value for the parameter.
 	BindParameter(arg interface{})
+
+	// Dialect returns the Dialect the query is written for. Clauses use the Dialect to render SQL which
+	// differs between database engines.
+	Dialect() Dialect
 }
 
 // Clause defines the interface implemented by all clauses used to describe different parts of a query.
@@ -44,68 +50,282 @@ type ColsClause interface {
 }
 
 type colsClause struct {
-	names []string
+	columns []Column
 }
 
 func (c *colsClause) clause() {}
 func (c *colsClause) cols()   {}
 
 func (c *colsClause) Names() []string {
-	return c.names
+	names := make([]string, len(c.columns))
+	for i, col := range c.columns {
+		names[i] = col.Name()
+	}
+	return names
 }
 
 func (c *colsClause) Write(w ClauseWriter) {
-	for i, n := range c.names {
+	for i, col := range c.columns {
 		if i > 0 {
 			w.WriteRune(',')
 		}
-		w.WriteString(n)
+		col.Write(w)
 	}
 }
 
 // Cols implements a factory for a ColsClause.
 func Cols(cols ...string) ColsClause {
+	columns := make([]Column, len(cols))
+	for i, c := range cols {
+		columns[i] = Col(c)
+	}
+
+	return Columns(columns...)
+}
+
+// Columns implements a factory for a ColsClause selecting arbitrary columns, such as aggregates or aliased
+// columns.
+func Columns(cols ...Column) ColsClause {
 	return &colsClause{
-		names: cols,
+		columns: cols,
+	}
+}
+
+// Column defines a single column or expression selected by a ColsClause.
+type Column interface {
+	// Name returns the name used as the key for the column's value in Values.
+	Name() string
+
+	// Write writes the column to the given writer.
+	Write(w ClauseWriter)
+
+	// As returns a copy of the column using alias as its name.
+	As(alias string) Column
+}
+
+type column struct {
+	name  string
+	alias string
+}
+
+func (c *column) Name() string {
+	if c.alias != "" {
+		return c.alias
 	}
+	return c.name
+}
+
+func (c *column) Write(w ClauseWriter) {
+	WriteIdentifier(w, c.name)
+	writeAlias(w, c.alias)
+}
+
+func (c *column) As(alias string) Column {
+	return &column{
+		name:  c.name,
+		alias: alias,
+	}
+}
+
+// Col creates a Column selecting the named column.
+func Col(name string) Column {
+	return &column{
+		name: name,
+	}
+}
+
+type aggregateColumn struct {
+	function string
+	column   string
+	alias    string
+}
+
+func (a *aggregateColumn) Name() string {
+	if a.alias != "" {
+		return a.alias
+	}
+	return a.function + "(" + a.column + ")"
+}
+
+func (a *aggregateColumn) Write(w ClauseWriter) {
+	w.WriteString(a.function)
+	w.WriteRune('(')
+	WriteIdentifier(w, a.column)
+	w.WriteRune(')')
+	writeAlias(w, a.alias)
+}
+
+func (a *aggregateColumn) As(alias string) Column {
+	return &aggregateColumn{
+		function: a.function,
+		column:   a.column,
+		alias:    alias,
+	}
+}
+
+// Count creates a Column counting the rows with non-null values in column. Pass "*" to count all rows.
+func Count(column string) Column {
+	return &aggregateColumn{function: "count", column: column}
+}
+
+// Sum creates a Column summing up the values of column.
+func Sum(column string) Column {
+	return &aggregateColumn{function: "sum", column: column}
+}
+
+// Avg creates a Column calculating the average of the values of column.
+func Avg(column string) Column {
+	return &aggregateColumn{function: "avg", column: column}
+}
+
+// Min creates a Column selecting the minimum of the values of column.
+func Min(column string) Column {
+	return &aggregateColumn{function: "min", column: column}
+}
+
+// Max creates a Column selecting the maximum of the values of column.
+func Max(column string) Column {
+	return &aggregateColumn{function: "max", column: column}
+}
+
+func writeAlias(w ClauseWriter, alias string) {
+	if alias == "" {
+		return
+	}
+	w.WriteString(" as ")
+	w.WriteString(w.Dialect().QuoteIdentifier(alias))
 }
 
 // --
 
-// TableClause implements a clause used to name a table.
+// TableClause implements a clause used to name a table or a join of tables.
 type TableClause interface {
 	Clause
+
+	// Join joins t with the given table using an inner join. Use On on the result to specify the join
+	// conditions.
+	Join(t TableClause) JoinClause
+
+	// LeftJoin joins t with the given table using a left outer join.
+	LeftJoin(t TableClause) JoinClause
+
+	// RightJoin joins t with the given table using a right outer join.
+	RightJoin(t TableClause) JoinClause
+
+	// CrossJoin joins t with the given table using a cross join.
+	CrossJoin(t TableClause) TableClause
+
 	table()
 }
 
+// NamedTableClause implements a TableClause naming a single table which may be given an alias.
+type NamedTableClause interface {
+	TableClause
+
+	// As returns a copy of the table using alias to refer to the table. Columns of an aliased table should
+	// be qualified with the alias, i.e. "m.id".
+	As(alias string) TableClause
+}
+
 type tableClause struct {
-	name string
+	name  string
+	alias string
 }
 
 func (t *tableClause) clause() {}
 func (t *tableClause) table()  {}
 
 func (t *tableClause) Write(w ClauseWriter) {
-	w.WriteString(t.name)
+	WriteIdentifier(w, t.name)
+	writeAlias(w, t.alias)
+}
+
+func (t *tableClause) As(alias string) TableClause {
+	return &tableClause{
+		name:  t.name,
+		alias: alias,
+	}
+}
+
+func (t *tableClause) Join(other TableClause) JoinClause      { return newJoin(t, "join", other) }
+func (t *tableClause) LeftJoin(other TableClause) JoinClause  { return newJoin(t, "left join", other) }
+func (t *tableClause) RightJoin(other TableClause) JoinClause { return newJoin(t, "right join", other) }
+func (t *tableClause) CrossJoin(other TableClause) TableClause {
+	return newJoin(t, "cross join", other)
 }
 
 // Table creates a TableClause from the single table name.
-func Table(name string) TableClause {
+func Table(name string) NamedTableClause {
 	return &tableClause{
 		name: name,
 	}
 }
 
 // From is an alias for Table supporting a more DSL style interface.
-func From(name string) TableClause {
+func From(name string) NamedTableClause {
 	return Table(name)
 }
 
 // Into is an alias for Table supporting a more DSL style interface.
-func Into(name string) TableClause {
+func Into(name string) NamedTableClause {
 	return Table(name)
 }
 
+// JoinClause defines a TableClause joining two tables.
+type JoinClause interface {
+	TableClause
+
+	// On sets the conditions used to join the tables. All conditions must be met.
+	On(conditions ...SearchCondition) TableClause
+}
+
+type joinClause struct {
+	left       TableClause
+	kind       string
+	right      TableClause
+	conditions []SearchCondition
+}
+
+func newJoin(left TableClause, kind string, right TableClause) *joinClause {
+	return &joinClause{
+		left:  left,
+		kind:  kind,
+		right: right,
+	}
+}
+
+func (j *joinClause) clause() {}
+func (j *joinClause) table()  {}
+
+func (j *joinClause) Write(w ClauseWriter) {
+	j.left.Write(w)
+	w.WriteRune(' ')
+	w.WriteString(j.kind)
+	w.WriteRune(' ')
+	j.right.Write(w)
+
+	if len(j.conditions) > 0 {
+		w.WriteString(" on ")
+		writeConditions(w, j.conditions)
+	}
+}
+
+func (j *joinClause) On(conditions ...SearchCondition) TableClause {
+	return &joinClause{
+		left:       j.left,
+		kind:       j.kind,
+		right:      j.right,
+		conditions: conditions,
+	}
+}
+
+func (j *joinClause) Join(other TableClause) JoinClause      { return newJoin(j, "join", other) }
+func (j *joinClause) LeftJoin(other TableClause) JoinClause  { return newJoin(j, "left join", other) }
+func (j *joinClause) RightJoin(other TableClause) JoinClause { return newJoin(j, "right join", other) }
+func (j *joinClause) CrossJoin(other TableClause) TableClause {
+	return newJoin(j, "cross join", other)
+}
+
 // --
 
 // SelectClause is an interface for clauses that can be used in select queries, such as where, order by,
@@ -120,6 +340,7 @@ type SelectClause interface {
 // OrderByClause defines an order by clause.
 type OrderByClause interface {
 	SelectClause
+	Cols() []OrderByCol
 	orderBy()
 }
 
@@ -136,6 +357,10 @@ func (o *orderByClause) clause()  {}
 func (o *orderByClause) sel()     {}
 func (o *orderByClause) orderBy() {}
 
+func (o *orderByClause) Cols() []OrderByCol {
+	return o.cols
+}
+
 func (o *orderByClause) Write(w ClauseWriter) {
 	if len(o.cols) == 0 {
 		return
@@ -146,7 +371,7 @@ func (o *orderByClause) Write(w ClauseWriter) {
 			w.WriteString(", ")
 		}
 
-		w.WriteString(c.Name)
+		WriteIdentifier(w, c.Name)
 		if c.Ascending {
 			w.WriteString(" asc")
 		} else {
@@ -179,6 +404,121 @@ func Desc(column string) OrderByCol {
 
 // --
 
+// GroupByClause defines a group by clause.
+type GroupByClause interface {
+	SelectClause
+	groupBy()
+}
+
+type groupByClause struct {
+	cols []string
+}
+
+func (g *groupByClause) clause()  {}
+func (g *groupByClause) sel()     {}
+func (g *groupByClause) groupBy() {}
+
+func (g *groupByClause) Write(w ClauseWriter) {
+	for i, c := range g.cols {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		WriteIdentifier(w, c)
+	}
+}
+
+// GroupBy constructs a new GroupByClause grouping by the given columns.
+func GroupBy(cols ...string) GroupByClause {
+	return &groupByClause{
+		cols: cols,
+	}
+}
+
+// HavingClause defines a having clause used to filter groups.
+type HavingClause interface {
+	SelectClause
+	having()
+}
+
+type havingClause struct {
+	conditions []SearchCondition
+}
+
+func (h *havingClause) clause() {}
+func (h *havingClause) sel()    {}
+func (h *havingClause) having() {}
+
+func (h *havingClause) Write(w ClauseWriter) {
+	writeConditions(w, h.conditions)
+}
+
+// Having constructs a new HavingClause. All conditions must be met by a group.
+func Having(conditions ...SearchCondition) HavingClause {
+	return &havingClause{
+		conditions: conditions,
+	}
+}
+
+// --
+
+// LimitClause defines a clause limiting the number of rows returned from a query.
+type LimitClause interface {
+	SelectClause
+	Limit() int
+}
+
+type limitClause struct {
+	limit int
+}
+
+func (l *limitClause) clause()    {}
+func (l *limitClause) sel()       {}
+func (l *limitClause) Limit() int { return l.limit }
+
+// Write writes l using the standard limit syntax. Queries executed by Tx let the Dialect render limits
+// instead.
+func (l *limitClause) Write(w ClauseWriter) {
+	w.WriteString("limit ")
+	w.BindParameter(l.limit)
+}
+
+// Limit constructs a new LimitClause limiting the query to return at most n rows.
+func Limit(n int) LimitClause {
+	return &limitClause{
+		limit: n,
+	}
+}
+
+// OffsetClause defines a clause skipping a number of rows returned from a query.
+type OffsetClause interface {
+	SelectClause
+	Offset() int
+}
+
+type offsetClause struct {
+	offset int
+}
+
+func (o *offsetClause) clause()     {}
+func (o *offsetClause) sel()        {}
+func (o *offsetClause) Offset() int { return o.offset }
+
+// Write writes o using the standard offset syntax. Queries executed by Tx let the Dialect render offsets
+// instead.
+func (o *offsetClause) Write(w ClauseWriter) {
+	w.WriteString("offset ")
+	w.BindParameter(o.offset)
+}
+
+// Offset constructs a new OffsetClause skipping the first n rows.
+func Offset(n int) OffsetClause {
+	return &offsetClause{
+		offset: n,
+	}
+}
+
+// --
+
 type WhereClause interface {
 	SelectClause
 	where()
@@ -196,11 +536,18 @@ func (wc *whereClause) clause() {}
 func (wc *whereClause) sel()    {}
 func (wc *whereClause) where()  {}
 func (wc *whereClause) Write(w ClauseWriter) {
-	if len(wc.conditions) == 0 {
-		return
+	writeConditions(w, wc.conditions)
+}
+
+func Where(conditions ...SearchCondition) WhereClause {
+	return &whereClause{
+		conditions: conditions,
 	}
+}
 
-	for i, c := range wc.conditions {
+// writeConditions writes all conditions joined with and.
+func writeConditions(w ClauseWriter, conditions []SearchCondition) {
+	for i, c := range conditions {
 		if i > 0 {
 			w.WriteString(" and ")
 		}
@@ -211,12 +558,71 @@ func (wc *whereClause) Write(w ClauseWriter) {
 	}
 }
 
-func Where(conditions ...SearchCondition) WhereClause {
-	return &whereClause{
+// --
+
+// junction implements a SearchCondition combining multiple conditions using a boolean operator.
+type junction struct {
+	operator   string
+	empty      string
+	conditions []SearchCondition
+}
+
+func (j *junction) Write(w ClauseWriter) {
+	if len(j.conditions) == 0 {
+		w.WriteString(j.empty)
+		return
+	}
+
+	for i, c := range j.conditions {
+		if i > 0 {
+			w.WriteRune(' ')
+			w.WriteString(j.operator)
+			w.WriteRune(' ')
+		}
+
+		w.WriteRune('(')
+		c.Write(w)
+		w.WriteRune(')')
+	}
+}
+
+// And creates a SearchCondition that is met when all of the given conditions are met. And without any
+// condition is always met.
+func And(conditions ...SearchCondition) SearchCondition {
+	return &junction{
+		operator:   "and",
+		empty:      "1 = 1",
 		conditions: conditions,
 	}
 }
 
+// Or creates a SearchCondition that is met when at least one of the given conditions is met. Or without
+// any condition is never met.
+func Or(conditions ...SearchCondition) SearchCondition {
+	return &junction{
+		operator:   "or",
+		empty:      "1 = 0",
+		conditions: conditions,
+	}
+}
+
+type notCondition struct {
+	condition SearchCondition
+}
+
+func (n *notCondition) Write(w ClauseWriter) {
+	w.WriteString("not (")
+	n.condition.Write(w)
+	w.WriteRune(')')
+}
+
+// Not creates a SearchCondition negating condition.
+func Not(condition SearchCondition) SearchCondition {
+	return &notCondition{
+		condition: condition,
+	}
+}
+
 // --
 
 type OperatorSearchCondition struct {
@@ -226,7 +632,7 @@ type OperatorSearchCondition struct {
 }
 
 func (o OperatorSearchCondition) Write(w ClauseWriter) {
-	w.WriteString(o.Column)
+	WriteIdentifier(w, o.Column)
 	w.WriteRune(' ')
 	w.WriteString(o.Operator)
 	w.WriteRune(' ')
@@ -273,13 +679,210 @@ func LE(column string, val interface{}) SearchCondition {
 	}
 }
 
+// NE creates a SearchCondition matching all rows where column is not equal to val.
+func NE(column string, val interface{}) SearchCondition {
+	return OperatorSearchCondition{
+		Column:   column,
+		Operator: "<>",
+		Value:    val,
+	}
+}
+
+type betweenClause struct {
+	column string
+	lower  interface{}
+	upper  interface{}
+}
+
+func (c *betweenClause) Write(w ClauseWriter) {
+	WriteIdentifier(w, c.column)
+	w.WriteString(" between ")
+	w.BindParameter(c.lower)
+	w.WriteString(" and ")
+	w.BindParameter(c.upper)
+}
+
+// Between creates a SearchCondition matching all rows where column is between lower and upper (both
+// inclusive).
+func Between(column string, lower, upper interface{}) SearchCondition {
+	return &betweenClause{
+		column: column,
+		lower:  lower,
+		upper:  upper,
+	}
+}
+
+// LikeEscapeChar is the escape character used by EscapeLike, the conditions created by Contains, HasPrefix
+// and HasSuffix as well as escaped LikeConditions.
+const LikeEscapeChar = '!'
+
+// LikeCondition defines a SearchCondition matching a column against a like pattern.
+type LikeCondition interface {
+	SearchCondition
+
+	// Escaped returns a copy of the condition which declares LikeEscapeChar as the pattern's escape
+	// character. Use it with patterns escaped using EscapeLike.
+	Escaped() LikeCondition
+}
+
+type likeClause struct {
+	column      string
+	pattern     string
+	not         bool
+	insensitive bool
+	escaped     bool
+}
+
+func (c *likeClause) Write(w ClauseWriter) {
+	operator := "like"
+	if c.not {
+		operator = "not like"
+	}
+
+	if c.insensitive && w.Dialect().SupportsILike() {
+		operator = "ilike"
+		if c.not {
+			operator = "not ilike"
+		}
+	} else if c.insensitive {
+		w.WriteString("lower(")
+		WriteIdentifier(w, c.column)
+		w.WriteString(") ")
+		w.WriteString(operator)
+		w.WriteString(" lower(")
+		w.BindParameter(c.pattern)
+		w.WriteRune(')')
+		c.writeEscape(w)
+		return
+	}
+
+	WriteIdentifier(w, c.column)
+	w.WriteRune(' ')
+	w.WriteString(operator)
+	w.WriteRune(' ')
+	w.BindParameter(c.pattern)
+	c.writeEscape(w)
+}
+
+func (c *likeClause) Escaped() LikeCondition {
+	escaped := *c
+	escaped.escaped = true
+	return &escaped
+}
+
+func (c *likeClause) writeEscape(w ClauseWriter) {
+	if c.escaped {
+		w.WriteString(" escape '")
+		w.WriteRune(LikeEscapeChar)
+		w.WriteRune('\'')
+	}
+}
+
+// Like creates a LikeCondition matching all rows where column matches the like pattern. pattern is passed
+// to the database as is; use EscapeLike to escape user input and call Escaped on the condition:
+//
+//	Like("text", "%"+EscapeLike(input)+"%").Escaped()
+func Like(column, pattern string) LikeCondition {
+	return &likeClause{
+		column:  column,
+		pattern: pattern,
+	}
+}
+
+// NotLike creates a LikeCondition matching all rows where column does not match the like pattern.
+func NotLike(column, pattern string) LikeCondition {
+	return &likeClause{
+		column:  column,
+		pattern: pattern,
+		not:     true,
+	}
+}
+
+// ILike creates a LikeCondition matching all rows where column matches the like pattern ignoring case.
+// Dialects not supporting the ilike operator compare the lower case values instead.
+func ILike(column, pattern string) LikeCondition {
+	return &likeClause{
+		column:      column,
+		pattern:     pattern,
+		insensitive: true,
+	}
+}
+
+// Contains creates a SearchCondition matching all rows where column contains s. s is escaped, so it may
+// contain any character including the like wildcards.
+func Contains(column, s string) SearchCondition {
+	return &likeClause{
+		column:  column,
+		pattern: "%" + EscapeLike(s) + "%",
+		escaped: true,
+	}
+}
+
+// HasPrefix creates a SearchCondition matching all rows where column starts with s. s is escaped, so it
+// may contain any character including the like wildcards.
+func HasPrefix(column, s string) SearchCondition {
+	return &likeClause{
+		column:  column,
+		pattern: EscapeLike(s) + "%",
+		escaped: true,
+	}
+}
+
+// HasSuffix creates a SearchCondition matching all rows where column ends with s. s is escaped, so it
+// may contain any character including the like wildcards.
+func HasSuffix(column, s string) SearchCondition {
+	return &likeClause{
+		column:  column,
+		pattern: "%" + EscapeLike(s),
+		escaped: true,
+	}
+}
+
+// EscapeLike escapes the like wildcards % and _ as well as the escape character in s using
+// LikeEscapeChar. Use the result in a pattern passed to Like, NotLike or ILike and call Escaped on the
+// resulting LikeCondition.
+func EscapeLike(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r == '%' || r == '_' || r == LikeEscapeChar {
+			b.WriteRune(LikeEscapeChar)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+type columnComparison struct {
+	left     string
+	operator string
+	right    string
+}
+
+func (c *columnComparison) Write(w ClauseWriter) {
+	WriteIdentifier(w, c.left)
+	w.WriteRune(' ')
+	w.WriteString(c.operator)
+	w.WriteRune(' ')
+	WriteIdentifier(w, c.right)
+}
+
+// EqCol creates a SearchCondition matching all rows where the columns left and right are equal. EqCol is
+// most useful to define join conditions.
+func EqCol(left, right string) SearchCondition {
+	return &columnComparison{
+		left:     left,
+		operator: "=",
+		right:    right,
+	}
+}
+
 type nullClause struct {
 	col string
 	not bool
 }
 
 func (c nullClause) Write(w ClauseWriter) {
-	w.WriteString(c.col)
+	WriteIdentifier(w, c.col)
 	w.WriteString(" is ")
 	if c.not {
 		w.WriteString("not ")
@@ -303,10 +906,25 @@ func IsNull(col string) SearchCondition {
 type inClause struct {
 	column string
 	values []interface{}
+	not    bool
 }
 
 func (c inClause) Write(w ClauseWriter) {
-	w.WriteString(c.column)
+	if len(c.values) == 0 {
+		// An empty list is not valid SQL. Write a predicate that is always false
+		// (or always true for not in) instead.
+		if c.not {
+			w.WriteString("1 = 1")
+		} else {
+			w.WriteString("1 = 0")
+		}
+		return
+	}
+
+	WriteIdentifier(w, c.column)
+	if c.not {
+		w.WriteString(" not")
+	}
 	w.WriteString(" in (")
 
 	for i, v := range c.values {
@@ -320,10 +938,19 @@ func (c inClause) Write(w ClauseWriter) {
 	w.WriteRune(')')
 }
 
-// In creates a WhereClause using the `in` operator.
+// In creates a WhereClause using the `in` operator. An empty list of values never matches.
 func In(column string, values ...interface{}) SearchCondition {
 	return &inClause{
 		column: column,
 		values: values,
 	}
 }
+
+// NotIn creates a WhereClause using the `not in` operator. An empty list of values always matches.
+func NotIn(column string, values ...interface{}) SearchCondition {
+	return &inClause{
+		column: column,
+		values: values,
+		not:    true,
+	}
+}
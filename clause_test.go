@@ -0,0 +1,167 @@
+// Copyright 2021 Alexander Metzner.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClauses(t *testing.T) {
+	tests := []struct {
+		clause   Clause
+		expected string
+	}{
+		{
+			clause:   Cols("id", "text"),
+			expected: `"id","text"`,
+		},
+		{
+			clause:   Columns(Col("text").As("t"), Count("*").As("cnt"), Sum("len"), Avg("len"), Min("id"), Max("id")),
+			expected: `"text" as "t",count(*) as "cnt",sum("len"),avg("len"),min("id"),max("id")`,
+		},
+		{
+			clause:   Where(Eq("a", 1), Or(Eq("b", 2), And(Eq("c", 3), Not(IsNull("d"))))),
+			expected: `("a" = ?) and (("b" = ?) or (("c" = ?) and (not ("d" is null))))`,
+		},
+		{
+			clause:   Where(Or(), And()),
+			expected: "(1 = 0) and (1 = 1)",
+		},
+		{
+			clause:   Where(NE("a", 1), Between("b", 1, 2)),
+			expected: `("a" <> ?) and ("b" between ? and ?)`,
+		},
+		{
+			clause:   Where(Like("a", "x%"), NotLike("b", "y%"), ILike("c", "z%")),
+			expected: `("a" like ?) and ("b" not like ?) and (lower("c") like lower(?))`,
+		},
+		{
+			clause:   Where(Contains("a", "x"), HasPrefix("b", "y"), HasSuffix("c", "z")),
+			expected: `("a" like ? escape '!') and ("b" like ? escape '!') and ("c" like ? escape '!')`,
+		},
+		{
+			clause:   Where(Like("a", "50!%%").Escaped(), NotLike("b", "%"+EscapeLike("a_b")).Escaped(), ILike("c", EscapeLike("x!")+"%").Escaped()),
+			expected: `("a" like ? escape '!') and ("b" not like ? escape '!') and (lower("c") like lower(?) escape '!')`,
+		},
+		{
+			clause:   Where(In("a", 1, 2), NotIn("b", 3), In("c"), NotIn("d")),
+			expected: `("a" in (?, ?)) and ("b" not in (?)) and (1 = 0) and (1 = 1)`,
+		},
+		{
+			clause:   Table("messages").As("m"),
+			expected: `"messages" as "m"`,
+		},
+		{
+			clause: Table("messages").As("m").
+				Join(Table("users").As("u")).On(EqCol("m.user_id", "u.id")).
+				LeftJoin(Table("attachments").As("a")).On(EqCol("a.message_id", "m.id"), IsNotNull("a.data")).
+				RightJoin(Table("groups")).On(EqCol("groups.id", "u.group_id")).
+				CrossJoin(Table("tags")),
+			expected: `"messages" as "m" join "users" as "u" on ("m"."user_id" = "u"."id") ` +
+				`left join "attachments" as "a" on ("a"."message_id" = "m"."id") and ("a"."data" is not null) ` +
+				`right join "groups" on ("groups"."id" = "u"."group_id") cross join "tags"`,
+		},
+		{
+			clause: Columns(Col("id"), Select(Cols("count(*)"), From("attachments").As("a"),
+				Where(EqCol("a.message_id", "m.id"))).As("attachments")),
+			expected: `"id",(select count(*) from "attachments" as "a" where ("a"."message_id" = "m"."id")) as "attachments"`,
+		},
+		{
+			clause: Where(
+				Exists(Select(Cols("id"), From("a"), Where(Eq("x", 1)))),
+				NotExists(Select(Cols("id"), From("b"))),
+				InSubquery("id", Select(Cols("id"), From("c"), Limit(1))),
+			),
+			expected: `(exists (select "id" from "a" where ("x" = ?))) and (not exists (select "id" from "b")) and ` +
+				`("id" in (select "id" from "c" limit ?))`,
+		},
+		{
+			clause:   GroupBy("a", "b"),
+			expected: `"a", "b"`,
+		},
+		{
+			clause:   Having(GT("count(*)", 1)),
+			expected: "(count(*) > ?)",
+		},
+		{
+			clause:   Columns(Col("order"), Col("e.user"), Col("e.*")),
+			expected: `"order","e"."user","e".*`,
+		},
+		{
+			clause:   Table("audit.events").As("e"),
+			expected: `"audit"."events" as "e"`,
+		},
+		{
+			clause:   OrderBy(Asc("order"), Desc("Created")),
+			expected: `"order" asc, "Created" desc`,
+		},
+		{
+			clause:   Cols(`"MixedCase"`, "lower(text)"),
+			expected: `"MixedCase",lower(text)`,
+		},
+	}
+
+	for _, test := range tests {
+		cb := NewDefaultClauseBuilder(&DefaultDialect{})
+		test.clause.Write(cb)
+		if cb.SQL() != test.expected {
+			t.Errorf("expected '%s' but got '%s'", test.expected, cb.SQL())
+		}
+	}
+}
+
+// iLikeDialect is a DefaultDialect supporting the ilike operator.
+type iLikeDialect struct {
+	DefaultDialect
+}
+
+func (d *iLikeDialect) NewClauseBuilder() QueryBuilder { return NewDefaultClauseBuilder(d) }
+func (d *iLikeDialect) SupportsILike() bool            { return true }
+
+func TestILike(t *testing.T) {
+	cb := (&iLikeDialect{}).NewClauseBuilder()
+	ILike("a", "x%").Write(cb)
+
+	if cb.SQL() != `"a" ilike ?` {
+		t.Errorf("expected ilike operator but got '%s'", cb.SQL())
+	}
+
+	cb = (&iLikeDialect{}).NewClauseBuilder()
+	ILike("a", EscapeLike("50%")+"%").Escaped().Write(cb)
+
+	if cb.SQL() != `"a" ilike ? escape '!'` {
+		t.Errorf("expected escaped ilike operator but got '%s'", cb.SQL())
+	}
+	if !reflect.DeepEqual(cb.Args(), []interface{}{"50!%%"}) {
+		t.Errorf("got unexpected args: %v", cb.Args())
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	actual := EscapeLike("100% of a_b!")
+	if actual != "100!% of a!_b!!" {
+		t.Errorf("got unexpected escaped string: %s", actual)
+	}
+}
+
+func TestColsClauseNames(t *testing.T) {
+	cols := Columns(Col("text").As("t"), Col("id"), Count("*").As("cnt"), Sum("len"))
+	expected := []string{"t", "id", "cnt", "sum(len)"}
+
+	if !reflect.DeepEqual(expected, cols.Names()) {
+		t.Errorf("expected %v but got %v", expected, cols.Names())
+	}
+}
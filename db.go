@@ -17,8 +17,14 @@ package depot
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"time"
 )
 
+// ErrTxOptionsMismatch is returned from BeginTx when a nested transaction requests options that are not
+// satisfied by the outer transaction already bound to the context.
+var ErrTxOptionsMismatch = errors.New("transaction options do not match outer transaction")
+
 // contextKeySessionType defines the type used to store a Session in a Context.
 type contextKeySessionType string
 
@@ -27,11 +33,59 @@ const contextKeySession contextKeySessionType = "session"
 
 // Options defines the options for a DB.
 type Options struct {
-	// Dialect defines the SQL dialect to generate. If not set a default dialect will be used.
+	// Dialect defines the SQL dialect to generate. If not set the Dialect registered for the driver is used
+	// (see RegisterDialect) or a default dialect if none has been registered.
 	Dialect Dialect
 
-	// When set to true all SQL statements will be logged using the log package.
+	// DriverName names the database/sql driver used to select a registered Dialect if Dialect is not set.
+	// Open sets it to the driver name given. If no Dialect has been registered for the name, the Dialect is
+	// selected by the type of the pool's driver.
+	DriverName string
+
+	// When set to true all SQL statements will be logged using the log package. This is a shortcut for
+	// setting Logger to NewStdLogger(nil, LevelDebug) and is ignored if Logger is set.
 	LogSQL bool
+
+	// Logger receives structured log messages for all statements executed as well as for beginning,
+	// committing and rolling back transactions. Statements are logged with LevelDebug, failed statements with
+	// LevelError. Values wrapped with Redacted are hidden from the logged arguments.
+	Logger Logger
+
+	// When set to true nested transactions are flattened into the outermost transaction instead of being
+	// backed by savepoints. Rolling back a flattened nested transaction rolls back the whole transaction.
+	FlattenNestedTx bool
+
+	// TxRetries defines the number of times RunInTx retries a transaction that failed with an error the
+	// Dialect considers retryable. Defaults to 0 which disables retries.
+	TxRetries int
+
+	// TxRetryBackoff computes the time to wait before the given retry attempt (starting with 1). If not set
+	// an exponential backoff starting at 10ms is used.
+	TxRetryBackoff func(attempt int) time.Duration
+
+	// StmtCacheSize defines the number of prepared statements cached by the DB. Statements are cached by
+	// their SQL and evicted least recently used first. A statement missing the cache is executed without
+	// preparing and is prepared after the transaction has finished. Defaults to 0 which disables the cache.
+	StmtCacheSize int
+
+	// Hooks defines interceptors called before and after every statement as well as when beginning,
+	// committing or rolling back a transaction. Before is called in the order given, After in reverse order.
+	Hooks []Hook
+}
+
+// ExponentialBackoff returns a backoff function usable as Options.TxRetryBackoff. The returned function
+// doubles the wait time with every attempt starting at initial and never exceeding max.
+func ExponentialBackoff(initial, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := initial
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
 }
 
 // DB provides the primary interface to interact with the persistence framework. It provides functions to
@@ -39,64 +93,188 @@ type Options struct {
 type DB struct {
 	pool    *sql.DB
 	options Options
+	stmts   *stmtCache
 }
 
 // New creates a new DB using connections from the given pool. Options may be empty in which case defaults
-// are used (which might not work with the database connecting to). It is recommended to at least provide a
-// matching Dialect.
+// are used. If no Dialect is given, the Dialect registered for Options.DriverName or the type of the pool's
+// driver is used. Make sure to import the matching engine package in this case.
 func New(pool *sql.DB, options Options) *DB {
 	if options.Dialect == nil {
-		options.Dialect = &DefaultDialect{}
+		options.Dialect = detectDialect(options.DriverName, pool.Driver())
 	}
 
-	return &DB{
+	if options.Logger == nil && options.LogSQL {
+		options.Logger = NewStdLogger(nil, LevelDebug)
+	}
+
+	if options.Logger != nil {
+		hooks := make([]Hook, 0, len(options.Hooks)+1)
+		hooks = append(hooks, &logHook{logger: options.Logger})
+		options.Hooks = append(hooks, options.Hooks...)
+	}
+
+	if options.TxRetryBackoff == nil {
+		options.TxRetryBackoff = ExponentialBackoff(10*time.Millisecond, time.Second)
+	}
+
+	db := &DB{
 		pool:    pool,
 		options: options,
 	}
+
+	if options.StmtCacheSize > 0 {
+		db.stmts = newStmtCache(pool, options.StmtCacheSize)
+	}
+
+	return db
 }
 
 // Open opens a new database pool and wraps it in a DB. This function resembles sql.Open (which is called)
-// from this function and uses defaults to connect to the database. For performance-critical code it is
-// recommended to use New with a preconstructed database pool.
+// from this function and uses defaults to connect to the database. If no Dialect is given, the Dialect
+// registered for driver is used. For performance-critical code it is recommended to use New with a
+// preconstructed database pool.
 func Open(driver, dsn string, options Options) (*DB, error) {
 	pool, err := sql.Open(driver, dsn)
 	if err != nil {
 		return nil, err
 	}
+
+	if options.DriverName == "" {
+		options.DriverName = driver
+	}
 	return New(pool, options), nil
 }
 
 // Close closes the depot and the underlying pool.
 func (f *DB) Close() {
+	if f.stmts != nil {
+		f.stmts.close()
+	}
 	f.pool.Close()
 }
 
-// BeginTx creates a begins a new transaction and binds it to ctx. If ctx already contains a transaction this
-// it is returned instead with it's txCount incremented.
-func (f *DB) BeginTx(ctx context.Context) (*Tx, context.Context, error) {
+// StmtCacheStats returns statistics about the prepared statement cache. All values are zero if the cache
+// is disabled.
+func (f *DB) StmtCacheStats() StmtCacheStats {
+	if f.stmts == nil {
+		return StmtCacheStats{}
+	}
+	return f.stmts.stats()
+}
+
+// TxOption defines a functional option used to customize the transaction started with BeginTx.
+type TxOption func(*sql.TxOptions)
+
+// IsolationLevel returns a TxOption setting the transaction's isolation level.
+func IsolationLevel(level sql.IsolationLevel) TxOption {
+	return func(o *sql.TxOptions) {
+		o.Isolation = level
+	}
+}
+
+// ReadOnly returns a TxOption marking the transaction as read-only.
+func ReadOnly() TxOption {
+	return func(o *sql.TxOptions) {
+		o.ReadOnly = true
+	}
+}
+
+// BeginTx creates a begins a new transaction and binds it to ctx. If ctx already contains a transaction a
+// nested transaction is created which joins the outer one. The nested transaction is backed by a savepoint
+// unless Options.FlattenNestedTx is set.
+//
+// opts may be used to set the isolation level or to request a read-only transaction. When joining an outer
+// transaction the requested options must be satisfied by the outer one: A nested call may request the
+// default isolation level or the same level as the outer transaction. A nested call may request a read-only
+// transaction from a read-write transaction but not vice versa. If the options do not match,
+// ErrTxOptionsMismatch is returned and the outer transaction is left untouched.
+func (f *DB) BeginTx(ctx context.Context, opts ...TxOption) (*Tx, context.Context, error) {
 	// TODO: Add support to call this function in parallel.
 
+	var txOpts sql.TxOptions
+	for _, opt := range opts {
+		opt(&txOpts)
+	}
+
 	if s, ok := GetTx(ctx); ok {
-		s.txCount++
-		return s, ctx, nil
+		if !s.satisfies(txOpts) {
+			return nil, ctx, ErrTxOptionsMismatch
+		}
+
+		nested, err := s.begin(ctx)
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		return nested, context.WithValue(ctx, contextKeySession, nested), nil
 	}
 
-	// TODO: How to provide transaction options?
-	tx, err := f.pool.BeginTx(ctx, nil)
+	id := newTxID()
+
+	i := intercept(ctx, f.options.Hooks, HookEvent{TxID: id, Op: "BeginTx"})
+	tx, err := f.pool.BeginTx(i.context(ctx), &txOpts)
+	i.finish(-1, err)
 	if err != nil {
 		return nil, ctx, err
 	}
 
 	s := &Tx{
-		options: &f.options,
-		tx:      tx,
-		txCount: 1,
-		ctx:     ctx,
+		options:   &f.options,
+		tx:        tx,
+		txOptions: txOpts,
+		ctx:       ctx,
+		id:        id,
+		stmts:     f.stmts,
 	}
+	s.root = s
 
 	return s, context.WithValue(ctx, contextKeySession, s), nil
 }
 
+// RunInTx runs fn inside a transaction which is bound to the Context passed to fn. The transaction is
+// committed if fn returns nil and rolled back if fn returns an error or panics. Panics are re-raised after
+// the rollback.
+//
+// If the transaction fails with an error the Dialect considers retryable, the whole transaction including
+// the call to fn is retried up to Options.TxRetries times waiting for Options.TxRetryBackoff between the
+// attempts. When ctx already carries a transaction, RunInTx runs fn in a nested transaction and never
+// retries as the outer transaction must be retried as a whole.
+func (f *DB) RunInTx(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	_, nested := GetTx(ctx)
+
+	for attempt := 1; ; attempt++ {
+		err := f.runInTx(ctx, fn, opts)
+		if err == nil || nested || attempt > f.options.TxRetries || !f.options.Dialect.IsRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(f.options.TxRetryBackoff(attempt)):
+		}
+	}
+}
+
+// runInTx runs a single attempt of RunInTx.
+func (f *DB) runInTx(ctx context.Context, fn func(ctx context.Context) error, opts []TxOption) error {
+	tx, ctx, err := f.BeginTx(ctx, opts...)
+	if err != nil {
+		return err
+	}
+
+	// Rolling back is a no-op once the transaction has been committed. This also releases the transaction
+	// if fn panics or Commit fails because fn marked the transaction as failed using Error.
+	defer tx.Rollback()
+
+	if err := fn(ctx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 // GetTx returns the transaction associated with the given Context and a boolean flag (ok) indicating if a
 // transaction has been registered with the given context.
 func GetTx(ctx context.Context) (*Tx, bool) {
@@ -0,0 +1,104 @@
+// Copyright 2021 Alexander Metzner.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depot
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+// testDriver is a database/sql driver used to test dialect detection. It does not support connecting.
+type testDriver struct{}
+
+func (d *testDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("not supported")
+}
+
+// testDialect is the Dialect registered for testDriver.
+type testDialect struct {
+	DefaultDialect
+}
+
+func init() {
+	sql.Register("depot-test", &testDriver{})
+	RegisterDialect("depot-test", func() Dialect { return &testDialect{} })
+	RegisterDialect("*depot.testDriver", func() Dialect { return &testDialect{} })
+}
+
+func TestDialectDetection(t *testing.T) {
+	t.Run("open", func(t *testing.T) {
+		db, err := Open("depot-test", "", Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		if _, ok := db.options.Dialect.(*testDialect); !ok {
+			t.Errorf("expected registered dialect but got %T", db.options.Dialect)
+		}
+	})
+
+	t.Run("driver type", func(t *testing.T) {
+		pool, err := sql.Open("depot-test", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		db := New(pool, Options{})
+		defer db.Close()
+
+		if _, ok := db.options.Dialect.(*testDialect); !ok {
+			t.Errorf("expected registered dialect but got %T", db.options.Dialect)
+		}
+	})
+
+	t.Run("explicit dialect", func(t *testing.T) {
+		db, err := Open("depot-test", "", Options{Dialect: &iLikeDialect{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		if _, ok := db.options.Dialect.(*iLikeDialect); !ok {
+			t.Errorf("expected given dialect but got %T", db.options.Dialect)
+		}
+	})
+
+	t.Run("unknown driver name", func(t *testing.T) {
+		pool, err := sql.Open("depot-test", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		db := New(pool, Options{DriverName: "unknown"})
+		defer db.Close()
+
+		if _, ok := db.options.Dialect.(*testDialect); !ok {
+			t.Errorf("expected dialect detected by driver type but got %T", db.options.Dialect)
+		}
+	})
+}
+
+func TestRegisterDialectPanicsOnDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+
+	RegisterDialect("depot-test", func() Dialect { return &testDialect{} })
+}
@@ -15,10 +15,15 @@
 package depot_test
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"os"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/halimath/depot"
 	"github.com/halimath/depot/engine/sqlite"
@@ -114,6 +119,804 @@ func TestReading(t *testing.T) {
 	tx.Commit()
 }
 
+func TestQueryIter(t *testing.T) {
+	prepareTestDB(t)
+
+	db, err := depot.Open("sqlite3", "./test-package.db", depot.Options{
+		Dialect: &sqlite.Dialect{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	tx, _, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	cursor, err := tx.QueryIter(cols, depot.From("messages"), depot.OrderBy(depot.Asc("id")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cursor.Close()
+
+	ids := make([]interface{}, 0)
+	for cursor.Next() {
+		ids = append(ids, cursor.Values()["id"])
+	}
+
+	if err := cursor.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ids) != 2 || ids[0] != "1" || ids[1] != "2" {
+		t.Errorf("got unexpected ids: %v", ids)
+	}
+}
+
+func TestLimitOffset(t *testing.T) {
+	prepareTestDB(t)
+
+	db, err := depot.Open("sqlite3", "./test-package.db", depot.Options{
+		Dialect: &sqlite.Dialect{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	tx, _, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	msgs, err := tx.QueryMany(cols, depot.From("messages"), depot.OrderBy(depot.Asc("id")), depot.Limit(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0]["id"] != "1" {
+		t.Errorf("expected first message but got %v", msgs)
+	}
+
+	msgs, err = tx.QueryMany(cols, depot.From("messages"), depot.OrderBy(depot.Asc("id")), depot.Offset(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0]["id"] != "2" {
+		t.Errorf("expected second message but got %v", msgs)
+	}
+
+	msgs, err = tx.QueryMany(cols, depot.From("messages"), depot.Limit(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 0 {
+		t.Errorf("expected no messages but got %v", msgs)
+	}
+}
+
+func TestKeysetPagination(t *testing.T) {
+	prepareTestDB(t)
+
+	db, err := depot.Open("sqlite3", "./test-package.db", depot.Options{
+		Dialect: &sqlite.Dialect{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	tx, _, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	orderBy := depot.OrderBy(depot.Asc("text"), depot.Asc("id"))
+
+	msgs, err := tx.QueryMany(cols, depot.From("messages"), orderBy, depot.Limit(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := depot.KeysetToken(msgs[0]["text"], msgs[0]["id"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := depot.SeekAfterToken(orderBy, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, err = tx.QueryMany(cols, depot.From("messages"), depot.Where(after), orderBy, depot.Limit(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msgs) != 1 || msgs[0]["id"] != "1" {
+		t.Errorf("expected second page to contain message 1 but got %v", msgs)
+	}
+}
+
+func TestSearchConditions(t *testing.T) {
+	prepareTestDB(t)
+
+	db, err := depot.Open("sqlite3", "./test-package.db", depot.Options{
+		Dialect: &sqlite.Dialect{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	tx, _, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	err = tx.InsertOne(depot.Into("messages"), depot.Values{"id": "3", "text": "100% Hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		condition depot.SearchCondition
+		expected  int
+	}{
+		{depot.NE("id", "1"), 2},
+		{depot.Between("id", "2", "3"), 2},
+		{depot.Like("text", "hello%"), 2},
+		{depot.NotLike("text", "hello%"), 1},
+		{depot.ILike("text", "%HELLO"), 1},
+		{depot.Contains("text", "0% "), 1},
+		{depot.NotLike("text", "%"+depot.EscapeLike("0% ")+"%").Escaped(), 2},
+		{depot.ILike("text", depot.EscapeLike("100% H")+"%").Escaped(), 1},
+		{depot.ILike("text", depot.EscapeLike("100_")+"%").Escaped(), 0},
+		{depot.HasPrefix("text", "hello"), 2},
+		{depot.In("id"), 0},
+		{depot.NotIn("id"), 3},
+		{depot.NotIn("id", "1", "2"), 1},
+	}
+
+	for _, test := range tests {
+		count, err := tx.QueryCount(depot.From("messages"), depot.Where(test.condition))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != test.expected {
+			t.Errorf("%#v: expected %d messages but got %d", test.condition, test.expected, count)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	prepareTestDB(t)
+
+	db, err := depot.Open("sqlite3", "./test-package.db", depot.Options{
+		Dialect: &sqlite.Dialect{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	tx, _, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	msgs, err := tx.QueryMany(
+		depot.Columns(depot.Col("m.id"), depot.Col("n.text").As("other_text")),
+		depot.From("messages").As("m").
+			LeftJoin(depot.Table("messages").As("n")).On(depot.NE("n.id", "1"), depot.EqCol("n.id", "m.id")),
+		depot.OrderBy(depot.Asc("m.id")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 messages but got %d", len(msgs))
+	}
+	if msgs[0]["m.id"] != "1" || msgs[0]["other_text"] != nil {
+		t.Errorf("got unexpected first message: %v", msgs[0])
+	}
+	if msgs[1]["m.id"] != "2" || msgs[1]["other_text"] != "hello, again" {
+		t.Errorf("got unexpected second message: %v", msgs[1])
+	}
+}
+
+func TestSubquery(t *testing.T) {
+	prepareTestDB(t)
+
+	db, err := depot.Open("sqlite3", "./test-package.db", depot.Options{
+		Dialect: &sqlite.Dialect{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	tx, _, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	withAttachment := depot.Select(depot.Cols("id"), depot.From("messages"), depot.Where(depot.IsNotNull("attachment")))
+
+	msgs, err := tx.QueryMany(
+		depot.Columns(depot.Col("id"), depot.Select(depot.Cols("count(*)"), depot.From("messages")).As("total")),
+		depot.From("messages"),
+		depot.Where(depot.InSubquery("id", withAttachment), depot.Exists(withAttachment)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msgs) != 1 || msgs[0]["id"] != "2" || msgs[0]["total"] != int64(2) {
+		t.Errorf("got unexpected messages: %v", msgs)
+	}
+
+	count, err := tx.QueryCount(depot.From("messages"), depot.Where(depot.NotExists(withAttachment)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected 0 messages but got %d", count)
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	prepareTestDB(t)
+
+	db, err := depot.Open("sqlite3", "./test-package.db", depot.Options{
+		Dialect: &sqlite.Dialect{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	tx, _, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	err = tx.InsertOne(depot.Into("messages"), depot.Values{"id": "3", "text": "hello, world"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := tx.QueryMany(
+		depot.Columns(depot.Col("text"), depot.Count("*").As("cnt")),
+		depot.From("messages"),
+		depot.GroupBy("text"),
+		depot.Having(depot.GT("count(*)", 1)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(groups) != 1 {
+		t.Fatalf("expected 1 group but got %d", len(groups))
+	}
+
+	if groups[0]["text"] != "hello, world" || groups[0]["cnt"] != int64(2) {
+		t.Errorf("got unexpected group: %v", groups[0])
+	}
+}
+
+func TestStmtCache(t *testing.T) {
+	prepareTestDB(t)
+
+	db, err := depot.Open("sqlite3", "./test-package.db", depot.Options{
+		Dialect:       &sqlite.Dialect{},
+		StmtCacheSize: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		err = db.RunInTx(ctx, func(ctx context.Context) error {
+			tx := depot.MustGetTx(ctx)
+
+			for _, id := range []string{"1", "2"} {
+				if _, err := tx.QueryOne(cols, depot.From("messages"), depot.Where(depot.Eq("id", id))); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := db.StmtCacheStats()
+	if stats.Misses != 2 || stats.Hits != 2 || stats.Size != 1 {
+		t.Errorf("got unexpected stats after repeated query: %#v", stats)
+	}
+
+	err = db.RunInTx(ctx, func(ctx context.Context) error {
+		tx := depot.MustGetTx(ctx)
+
+		if _, err := tx.QueryCount(depot.From("messages")); err != nil {
+			return err
+		}
+		if _, err := tx.QueryMany(cols, depot.From("messages")); err != nil {
+			return err
+		}
+		_, err := tx.QueryOne(cols, depot.From("messages"), depot.Where(depot.Eq("id", "1")))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats = db.StmtCacheStats()
+	if stats.Misses != 4 || stats.Hits != 3 || stats.Size != 2 {
+		t.Errorf("got unexpected stats after eviction: %#v", stats)
+	}
+}
+
+func TestStmtCacheSingleConnection(t *testing.T) {
+	prepareTestDB(t)
+
+	pool, err := sql.Open("sqlite3", "./test-package.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	pool.SetMaxOpenConns(1)
+
+	db := depot.New(pool, depot.Options{
+		Dialect:       &sqlite.Dialect{},
+		StmtCacheSize: 10,
+	})
+
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err = db.RunInTx(ctx, func(ctx context.Context) error {
+			_, err := depot.MustGetTx(ctx).QueryMany(cols, depot.From("messages"))
+			return err
+		})
+		cancel()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := db.StmtCacheStats()
+	if stats.Misses != 1 || stats.Hits != 1 || stats.Size != 1 {
+		t.Errorf("got unexpected stats: %#v", stats)
+	}
+}
+
+type hookKey struct{}
+
+type recordingHook struct {
+	events []depot.HookEvent
+}
+
+func (h *recordingHook) Before(ctx context.Context, event *depot.HookEvent) context.Context {
+	return context.WithValue(ctx, hookKey{}, event.Op)
+}
+
+func (h *recordingHook) After(ctx context.Context, event *depot.HookEvent) {
+	if ctx.Value(hookKey{}) != event.Op {
+		panic("context returned from Before has not been passed to After")
+	}
+	h.events = append(h.events, *event)
+}
+
+func TestHooks(t *testing.T) {
+	prepareTestDB(t)
+
+	hook := &recordingHook{}
+
+	db, err := depot.Open("sqlite3", "./test-package.db", depot.Options{
+		Dialect: &sqlite.Dialect{},
+		Hooks:   []depot.Hook{hook},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	tx, ctx, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.InsertOne(depot.Into("messages"), depot.Values{"id": "3", "text": "hello, hooks"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tx.QueryMany(cols, depot.From("messages")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tx.QueryOne(cols, depot.From("messages"), depot.Where(depot.Eq("id", "4"))); !errors.Is(err, depot.ErrNoResult) {
+		t.Fatalf("expected no result but got %v", err)
+	}
+
+	nested, _, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := nested.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		op   string
+		rows int64
+	}{
+		{"BeginTx", -1},
+		{"InsertOne", 1},
+		{"QueryMany", 3},
+		{"QueryOne", 0},
+		{"Savepoint", -1},
+		{"RollbackToSavepoint", -1},
+		{"ReleaseSavepoint", -1},
+		{"Commit", -1},
+	}
+
+	if len(hook.events) != len(expected) {
+		t.Fatalf("expected %d events but got %d: %v", len(expected), len(hook.events), hook.events)
+	}
+
+	for i, e := range expected {
+		got := hook.events[i]
+		if got.Op != e.op || got.Rows != e.rows || got.Err != nil {
+			t.Errorf("expected event %d to be %s with %d rows but got %#v", i, e.op, e.rows, got)
+		}
+	}
+
+	if hook.events[1].SQL != `insert into "messages" ("id", "text") values (?, ?)` ||
+		!reflect.DeepEqual(hook.events[1].Args, []interface{}{"3", "hello, hooks"}) {
+		t.Errorf("got unexpected insert event: %#v", hook.events[1])
+	}
+}
+
+func TestLogger(t *testing.T) {
+	prepareTestDB(t)
+
+	var buf bytes.Buffer
+
+	db, err := depot.Open("sqlite3", "./test-package.db", depot.Options{
+		Dialect: &sqlite.Dialect{},
+		Logger:  depot.NewJSONLogger(&buf, depot.LevelDebug),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	tx, _, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	err = tx.InsertOne(depot.Into("messages"), depot.Values{"id": "3", "text": depot.Redacted("secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vals, err := tx.QueryOne(cols, depot.From("messages"), depot.Where(depot.Eq("id", "3")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vals["text"] != "secret" {
+		t.Errorf("expected redacted value to be stored but got %v", vals["text"])
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []map[string]interface{}
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var entry map[string]interface{}
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 log entries but got %d: %v", len(entries), entries)
+	}
+
+	for i, msg := range []string{"BeginTx", "InsertOne", "QueryOne", "Commit"} {
+		if entries[i]["msg"] != msg || entries[i]["level"] != "DEBUG" {
+			t.Errorf("got unexpected entry %d: %v", i, entries[i])
+		}
+		if entries[i]["tx"] == "" || entries[i]["tx"] != entries[0]["tx"] {
+			t.Errorf("expected all entries to carry the same tx id but got %v", entries[i])
+		}
+	}
+
+	if !reflect.DeepEqual(entries[1]["args"], []interface{}{"3", "[REDACTED]"}) {
+		t.Errorf("expected redacted args but got %v", entries[1]["args"])
+	}
+}
+
+func TestTxOptions(t *testing.T) {
+	prepareTestDB(t)
+
+	db, err := depot.Open("sqlite3", "./test-package.db", depot.Options{
+		Dialect: &sqlite.Dialect{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	tx, ctx, err := db.BeginTx(ctx, depot.IsolationLevel(sql.LevelSerializable))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	nested, _, err := db.BeginTx(ctx, depot.ReadOnly())
+	if err != nil {
+		t.Fatalf("expected read-only nested tx to join but got %s", err)
+	}
+	if err := nested.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = db.BeginTx(ctx, depot.IsolationLevel(sql.LevelReadCommitted))
+	if !errors.Is(err, depot.ErrTxOptionsMismatch) {
+		t.Errorf("expected ErrTxOptionsMismatch but got %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNestedTx(t *testing.T) {
+	prepareTestDB(t)
+
+	db, err := depot.Open("sqlite3", "./test-package.db", depot.Options{
+		Dialect: &sqlite.Dialect{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	tx, ctx, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	insert := func(id string, commit bool) {
+		nested, _, err := db.BeginTx(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer nested.Rollback()
+
+		err = nested.InsertOne(depot.Into("messages"), depot.Values{"id": id, "text": "nested"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if commit {
+			if err := nested.Commit(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	insert("3", true)
+	insert("4", false)
+
+	count, err := tx.QueryCount(depot.From("messages"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 messages but got %d", count)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFlattenedNestedTx(t *testing.T) {
+	prepareTestDB(t)
+
+	db, err := depot.Open("sqlite3", "./test-package.db", depot.Options{
+		Dialect:         &sqlite.Dialect{},
+		FlattenNestedTx: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	tx, ctx, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	nested, _, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := nested.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.Commit(); !errors.Is(err, depot.ErrRollback) {
+		t.Errorf("expected ErrRollback but got %v", err)
+	}
+}
+
+// errRetry is used to simulate a retryable error.
+var errRetry = errors.New("retry")
+
+// retryDialect extends the SQLite dialect to treat errRetry as retryable.
+type retryDialect struct {
+	sqlite.Dialect
+}
+
+func (d *retryDialect) IsRetryable(err error) bool {
+	return errors.Is(err, errRetry)
+}
+
+func TestRunInTx(t *testing.T) {
+	prepareTestDB(t)
+
+	db, err := depot.Open("sqlite3", "./test-package.db", depot.Options{
+		Dialect:   &retryDialect{},
+		TxRetries: 2,
+		TxRetryBackoff: func(int) time.Duration {
+			return time.Millisecond
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	attempts := 0
+	err = db.RunInTx(ctx, func(ctx context.Context) error {
+		attempts++
+		err := depot.MustGetTx(ctx).InsertOne(depot.Into("messages"), depot.Values{"id": "3", "text": "retried"})
+		if err != nil {
+			return err
+		}
+		if attempts < 2 {
+			return errRetry
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts but got %d", attempts)
+	}
+
+	err = db.RunInTx(ctx, func(ctx context.Context) error {
+		depot.MustGetTx(ctx).DeleteMany(depot.From("messages"))
+		return errors.New("failed")
+	})
+	if err == nil {
+		t.Errorf("expected error")
+	}
+
+	func() {
+		defer func() {
+			if p := recover(); p == nil {
+				t.Errorf("expected panic to be re-raised")
+			}
+		}()
+		db.RunInTx(ctx, func(ctx context.Context) error {
+			depot.MustGetTx(ctx).DeleteMany(depot.From("messages"))
+			panic("failed")
+		})
+	}()
+
+	err = db.RunInTx(ctx, func(ctx context.Context) error {
+		count, err := depot.MustGetTx(ctx).QueryCount(depot.From("messages"))
+		if err != nil {
+			return err
+		}
+		if count != 3 {
+			t.Errorf("expected 3 messages but got %d", count)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunInTxReleasesFailedTx(t *testing.T) {
+	prepareTestDB(t)
+
+	pool, err := sql.Open("sqlite3", "./test-package.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := depot.New(pool, depot.Options{
+		Dialect: &sqlite.Dialect{},
+	})
+	defer db.Close()
+
+	failure := errors.New("failed")
+
+	err = db.RunInTx(context.Background(), func(ctx context.Context) error {
+		tx := depot.MustGetTx(ctx)
+		if _, err := tx.DeleteMany(depot.From("messages")); err != nil {
+			return err
+		}
+		tx.Error(failure)
+		return nil
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected error passed to Error but got %v", err)
+	}
+
+	if inUse := pool.Stats().InUse; inUse != 0 {
+		t.Errorf("expected all connections to be released but got %d in use", inUse)
+	}
+
+	var count int
+	err = db.RunInTx(context.Background(), func(ctx context.Context) (err error) {
+		count, err = depot.MustGetTx(ctx).QueryCount(depot.From("messages"))
+		return
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected failed transaction to be rolled back but got %d messages", count)
+	}
+}
+
 func TestInsert(t *testing.T) {
 	prepareTestDB(t)
 
@@ -162,6 +965,177 @@ func TestInsert(t *testing.T) {
 	}
 }
 
+func TestConstraintErrors(t *testing.T) {
+	prepareTestDB(t)
+
+	db, err := depot.Open("sqlite3", "./test-package.db", depot.Options{
+		Dialect: &sqlite.Dialect{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	tx, _, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	err = tx.InsertOne(depot.Into("messages"), depot.Values{"id": "1", "text": "duplicate"})
+	if !errors.Is(err, depot.ErrUniqueViolation) {
+		t.Fatalf("expected unique violation but got %v", err)
+	}
+
+	var dbErr *depot.DatabaseError
+	if !errors.As(err, &dbErr) || dbErr.Column != "id" {
+		t.Errorf("expected column to be set but got %#v", err)
+	}
+
+	err = tx.InsertOne(depot.Into("messages"), depot.Values{"id": "3", "text": nil})
+	if !errors.Is(err, depot.ErrNotNullViolation) {
+		t.Fatalf("expected not null violation but got %v", err)
+	}
+
+	if !errors.As(err, &dbErr) || dbErr.Column != "text" {
+		t.Errorf("expected column to be set but got %#v", err)
+	}
+}
+
+func TestInsertMany(t *testing.T) {
+	prepareTestDB(t)
+
+	// A limit of 5 parameters forces chunks of a single row for two columns.
+	db, err := depot.Open("sqlite3", "./test-package.db", depot.Options{
+		Dialect: &sqlite.Dialect{ParameterLimit: 5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	tx, _, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	err = tx.InsertMany(depot.Into("messages"), []depot.Values{
+		{"id": "3", "text": "three"},
+		{"id": "4", "text": "four"},
+		{"id": "5", "text": "five"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := tx.QueryCount(depot.From("messages"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 5 {
+		t.Errorf("expected 5 messages but got %d", count)
+	}
+
+	err = tx.InsertMany(depot.Into("messages"), []depot.Values{
+		{"id": "6", "text": "six"},
+		{"id": "7", "attachment": nil},
+	})
+	if err == nil {
+		t.Error("expected error when inserting rows with different columns")
+	}
+}
+
+func TestInsertReturning(t *testing.T) {
+	prepareTestDB(t)
+
+	db, err := depot.Open("sqlite3", "./test-package.db", depot.Options{
+		Dialect: &sqlite.Dialect{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	tx, _, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("create table notes (id integer primary key autoincrement, text varchar not null)"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := int64(1); i <= 2; i++ {
+		generated, err := tx.InsertOneReturning(depot.Into("notes"), depot.Values{"text": "note"}, depot.Cols("id"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		id, ok := generated.GetInt64("id")
+		if !ok || id != i {
+			t.Errorf("expected generated id %d but got %v", i, generated)
+		}
+	}
+
+	_, err = tx.InsertOneReturning(depot.Into("notes"), depot.Values{"text": "note"}, depot.Cols("id", "text"))
+	if err == nil {
+		t.Error("expected error when returning multiple columns using last insert id")
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	prepareTestDB(t)
+
+	db, err := depot.Open("sqlite3", "./test-package.db", depot.Options{
+		Dialect: &sqlite.Dialect{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	tx, _, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	err = tx.Upsert(depot.Into("messages"), depot.Values{"id": "1", "text": "hello, upsert"}, depot.Cols("id"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tx.Upsert(depot.Into("messages"), depot.Values{"id": "3", "text": "hello, new"}, depot.Cols("id"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tx.Upsert(depot.Into("messages"), depot.Values{"id": "2", "text": "ignored"}, depot.Cols("id"), depot.Cols())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, err := tx.QueryMany(depot.Cols("id", "text"), depot.From("messages"), depot.OrderBy(depot.Asc("id")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msgs) != 3 {
+		t.Fatalf("expected 3 messages but got %d", len(msgs))
+	}
+	for i, text := range []string{"hello, upsert", "hello, again", "hello, new"} {
+		if msgs[i]["text"] != text {
+			t.Errorf("expected text %q for message %d but got %v", text, i, msgs[i]["text"])
+		}
+	}
+}
+
 func TestUpdate(t *testing.T) {
 	prepareTestDB(t)
 
@@ -180,7 +1154,7 @@ func TestUpdate(t *testing.T) {
 	}
 	defer tx.Rollback()
 
-	err = tx.UpdateMany(
+	n, err := tx.UpdateMany(
 		depot.Table("messages"),
 		depot.Values{"text": "hello, one more time"},
 		depot.Where(depot.Eq("id", "2")),
@@ -188,6 +1162,21 @@ func TestUpdate(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	if n != 1 {
+		t.Errorf("expected 1 updated row but got %d", n)
+	}
+
+	n, err = tx.UpdateMany(
+		depot.Table("messages"),
+		depot.Values{"text": "hello, nobody"},
+		depot.Where(depot.Eq("id", "3")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("expected 0 updated rows but got %d", n)
+	}
 
 	msgs, err := tx.QueryMany(cols, depot.From("messages"), depot.OrderBy(depot.Desc("id")))
 	if err != nil {
@@ -202,6 +1191,43 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+func TestUpdateExpression(t *testing.T) {
+	prepareTestDB(t)
+
+	db, err := depot.Open("sqlite3", "./test-package.db", depot.Options{
+		Dialect: &sqlite.Dialect{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	tx, _, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.UpdateMany(
+		depot.Table("messages"),
+		depot.Values{"text": depot.Expr("text || ?", "!")},
+		depot.Where(depot.Eq("id", "1")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vals, err := tx.QueryOne(cols, depot.From("messages"), depot.Where(depot.Eq("id", "1")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if vals["text"] != "hello, world!" {
+		t.Errorf("got unexpected text: %v", vals["text"])
+	}
+}
+
 func TestDelete(t *testing.T) {
 	prepareTestDB(t)
 
@@ -229,10 +1255,13 @@ func TestDelete(t *testing.T) {
 		t.Errorf("expected 2 messages but got %d", count)
 	}
 
-	err = tx.DeleteMany(depot.From("messages"))
+	n, err := tx.DeleteMany(depot.From("messages"))
 	if err != nil {
 		t.Errorf("expected no error but got: %s", err)
 	}
+	if n != 2 {
+		t.Errorf("expected 2 deleted rows but got %d", n)
+	}
 
 	count, err = tx.QueryCount(depot.From("messages"))
 	if err != nil {
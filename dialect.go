@@ -15,7 +15,10 @@
 package depot
 
 import (
+	"fmt"
 	"strings"
+	"sync"
+	"unicode"
 )
 
 // QueryBuilder defines the interface for types that are used to build clauses.
@@ -26,21 +29,111 @@ type QueryBuilder interface {
 	Args() []interface{}
 }
 
+// ReturningStyle defines how a Dialect reports values generated by the database when inserting a row.
+type ReturningStyle int
+
+const (
+	// LastInsertID reads a single generated value using sql.Result.LastInsertId.
+	LastInsertID ReturningStyle = iota
+
+	// ReturningClause appends a returning clause to the insert statement which can return any number of
+	// columns.
+	ReturningClause
+
+	// OutputClause adds an output clause as supported by SQL Server to the insert statement which can
+	// return any number of columns.
+	OutputClause
+)
+
 // Dialect abstracts the differences in SQL for different database engines.
 type Dialect interface {
 	// NewClauseBuilder creates a new QueryBuilder matching the selected database.
 	NewClauseBuilder() QueryBuilder
+
+	// Savepoint returns the statement creating a savepoint with the given name.
+	Savepoint(name string) string
+
+	// RollbackToSavepoint returns the statement rolling back to the savepoint with the given name.
+	RollbackToSavepoint(name string) string
+
+	// ReleaseSavepoint returns the statement releasing the savepoint with the given name. Dialects for
+	// databases that do not support releasing savepoints return an empty string.
+	ReleaseSavepoint(name string) string
+
+	// IsRetryable reports whether err signals a transient failure (such as a serialization failure or a
+	// deadlock) which may succeed when the whole transaction is retried.
+	IsRetryable(err error) bool
+
+	// WriteLimitOffset writes the clauses to limit the query to return at most limit rows skipping the
+	// first offset rows. A negative limit requests no limit; an offset of 0 requests no offset. ordered
+	// reports whether the query contains an order by clause, which some databases require for paging.
+	// WriteLimitOffset is never called with a limit of 0; such queries use an always false condition.
+	WriteLimitOffset(w ClauseWriter, limit, offset int, ordered bool)
+
+	// SupportsRowValues reports whether the database supports comparing row values such as (a, b) > (?, ?).
+	SupportsRowValues() bool
+
+	// SupportsILike reports whether the database supports the case insensitive ilike operator.
+	SupportsILike() bool
+
+	// Returning returns the style used to read generated values when inserting a row.
+	Returning() ReturningStyle
+
+	// WriteUpsert writes a statement inserting values into table. If the insert conflicts with an existing
+	// row on conflictCols, the row's updateCols are set to the values given. An empty updateCols requests
+	// to leave the existing row unchanged.
+	WriteUpsert(w ClauseWriter, into TableClause, values Values, conflictCols, updateCols []string)
+
+	// MaxParameters returns the maximum number of parameters that can be bound to a single statement.
+	MaxParameters() int
+
+	// MaxRows returns the maximum number of rows inserted by a single statement or 0 if the number of rows
+	// is only limited by MaxParameters.
+	MaxRows() int
+
+	// CurrentTimestamp returns the SQL expression evaluating to the current timestamp.
+	CurrentTimestamp() string
+
+	// QuoteIdentifier quotes a single (unqualified) identifier, such as a table or column name.
+	QuoteIdentifier(name string) string
+
+	// TranslateError translates an error reported by the driver into a *DatabaseError if the error can be
+	// classified. All other errors are returned unchanged.
+	TranslateError(err error) error
+
+	// ConvertValue converts val read from a column of the database type typeName (as reported by
+	// sql.ColumnType.DatabaseTypeName) into the value stored in Values. Dialects use it to convert the
+	// driver's representation of values, such as time values returned as strings, into Go types.
+	ConvertValue(typeName string, val interface{}) interface{}
 }
 
 // --
 
+// DefaultClauseBuilder implements a QueryBuilder using ? as parameter placeholders.
 type DefaultClauseBuilder struct {
-	sql  strings.Builder
-	args []interface{}
+	dialect Dialect
+	sql     strings.Builder
+	args    []interface{}
 }
 
 var _ QueryBuilder = &DefaultClauseBuilder{}
 
+// NewDefaultClauseBuilder creates a new DefaultClauseBuilder bound to dialect.
+func NewDefaultClauseBuilder(dialect Dialect) *DefaultClauseBuilder {
+	return &DefaultClauseBuilder{
+		dialect: dialect,
+	}
+}
+
+// Dialect returns the Dialect the builder has been created for. It returns a DefaultDialect if the builder
+// has not been bound to a dialect.
+func (b *DefaultClauseBuilder) Dialect() Dialect {
+	if b.dialect == nil {
+		return &DefaultDialect{}
+	}
+	return b.dialect
+}
+
 func (b *DefaultClauseBuilder) WriteString(s string) { b.sql.WriteString(s) }
 func (b *DefaultClauseBuilder) WriteRune(r rune)     { b.sql.WriteRune(r) }
 func (b *DefaultClauseBuilder) SQL() string          { return b.sql.String() }
@@ -58,5 +151,213 @@ type DefaultDialect struct {
 var _ Dialect = &DefaultDialect{}
 
 func (d *DefaultDialect) NewClauseBuilder() QueryBuilder {
-	return &DefaultClauseBuilder{}
+	return NewDefaultClauseBuilder(d)
+}
+
+// Savepoint returns the standard SQL statement to create a savepoint.
+func (d *DefaultDialect) Savepoint(name string) string {
+	return "savepoint " + name
+}
+
+// RollbackToSavepoint returns the standard SQL statement to roll back to a savepoint.
+func (d *DefaultDialect) RollbackToSavepoint(name string) string {
+	return "rollback to savepoint " + name
+}
+
+// ReleaseSavepoint returns the standard SQL statement to release a savepoint.
+func (d *DefaultDialect) ReleaseSavepoint(name string) string {
+	return "release savepoint " + name
+}
+
+// IsRetryable returns false as the default dialect does not know about any retryable errors.
+func (d *DefaultDialect) IsRetryable(err error) bool {
+	return false
+}
+
+// WriteLimitOffset writes the standard limit and offset clauses.
+func (d *DefaultDialect) WriteLimitOffset(w ClauseWriter, limit, offset int, ordered bool) {
+	if limit >= 0 {
+		w.WriteString(" limit ")
+		w.BindParameter(limit)
+	}
+
+	if offset > 0 {
+		w.WriteString(" offset ")
+		w.BindParameter(offset)
+	}
+}
+
+// SupportsRowValues returns false, so the default dialect expands row value comparisons.
+func (d *DefaultDialect) SupportsRowValues() bool {
+	return false
+}
+
+// SupportsILike returns false, so the default dialect compares lower case values instead.
+func (d *DefaultDialect) SupportsILike() bool {
+	return false
+}
+
+// Returning returns LastInsertID as a returning clause is not part of standard SQL.
+func (d *DefaultDialect) Returning() ReturningStyle {
+	return LastInsertID
+}
+
+// WriteUpsert writes an insert statement with an on conflict clause as supported by PostgreSQL and SQLite.
+func (d *DefaultDialect) WriteUpsert(w ClauseWriter, into TableClause, values Values, conflictCols, updateCols []string) {
+	WriteInsert(w, into, values)
+
+	w.WriteString(" on conflict (")
+	for i, col := range conflictCols {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		WriteIdentifier(w, col)
+	}
+	w.WriteRune(')')
+
+	if len(updateCols) == 0 {
+		w.WriteString(" do nothing")
+		return
+	}
+
+	w.WriteString(" do update set ")
+	for i, col := range updateCols {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		WriteIdentifier(w, col)
+		w.WriteString(" = excluded.")
+		WriteIdentifier(w, col)
+	}
+}
+
+// MaxRows returns 0 as the standard does not limit the number of rows inserted by a single statement.
+func (d *DefaultDialect) MaxRows() int {
+	return 0
+}
+
+// MaxParameters returns 999 which is the smallest limit of all supported databases (used by SQLite prior
+// to 3.32).
+func (d *DefaultDialect) MaxParameters() int {
+	return 999
+}
+
+// CurrentTimestamp returns the standard SQL current_timestamp function.
+func (d *DefaultDialect) CurrentTimestamp() string {
+	return "current_timestamp"
+}
+
+// TranslateError returns err unchanged as the default dialect does not know about any driver errors.
+func (d *DefaultDialect) TranslateError(err error) error {
+	return err
+}
+
+// ConvertValue returns val unchanged.
+func (d *DefaultDialect) ConvertValue(typeName string, val interface{}) interface{} {
+	return val
+}
+
+// QuoteIdentifier quotes name using double quotes as defined by standard SQL.
+func (d *DefaultDialect) QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// WriteIdentifier writes the possibly qualified identifier name (such as "audit.events") to w quoting each
+// part using the writer's Dialect. A * is written without quotes. Names that are not made up of plain
+// identifiers, such as expressions like count(*) or names that have already been quoted, are written
+// verbatim.
+func WriteIdentifier(w ClauseWriter, name string) {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		if !isPlainIdentifier(p) && !(p == "*" && i == len(parts)-1) {
+			w.WriteString(name)
+			return
+		}
+	}
+
+	for i, p := range parts {
+		if i > 0 {
+			w.WriteRune('.')
+		}
+
+		if p == "*" {
+			w.WriteRune('*')
+		} else {
+			w.WriteString(w.Dialect().QuoteIdentifier(p))
+		}
+	}
+}
+
+// isPlainIdentifier reports whether s is a regular identifier consisting of letters, digits and underscores
+// not starting with a digit.
+func isPlainIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i, r := range s {
+		switch {
+		case r == '_', unicode.IsLetter(r):
+		case unicode.IsDigit(r) && i > 0:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// --
+
+var (
+	dialectsMu sync.RWMutex
+	dialects   = make(map[string]func() Dialect)
+)
+
+// RegisterDialect makes a Dialect available for the database/sql driver given by name. name is either the
+// name the driver is registered with (as passed to sql.Open) or the type of the driver as formatted by %T,
+// such as "*pq.Driver", which is used to detect the Dialect for a pool passed to New. factory is called to
+// create a new Dialect for every DB. The engine packages register their dialects when imported. If
+// RegisterDialect is called twice with the same name or if factory is nil, it panics.
+func RegisterDialect(name string, factory func() Dialect) {
+	dialectsMu.Lock()
+	defer dialectsMu.Unlock()
+
+	if factory == nil {
+		panic("depot: RegisterDialect factory is nil")
+	}
+
+	if _, dup := dialects[name]; dup {
+		panic("depot: RegisterDialect called twice for " + name)
+	}
+
+	dialects[name] = factory
+}
+
+// lookupDialect creates the Dialect registered for name. It returns nil if no Dialect has been registered.
+func lookupDialect(name string) Dialect {
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+
+	factory, ok := dialects[name]
+	if !ok {
+		return nil
+	}
+	return factory()
+}
+
+// detectDialect returns the Dialect registered for driverName or for the type of driver. It returns a
+// DefaultDialect if no matching Dialect has been registered.
+func detectDialect(driverName string, driver interface{}) Dialect {
+	if driverName != "" {
+		if d := lookupDialect(driverName); d != nil {
+			return d
+		}
+	}
+
+	if d := lookupDialect(fmt.Sprintf("%T", driver)); d != nil {
+		return d
+	}
+
+	return &DefaultDialect{}
 }
@@ -14,7 +14,198 @@
 
 package mysql
 
-import "github.com/halimath/depot"
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/halimath/depot"
+)
+
+const (
+	// errLockWaitTimeout is the MySQL error number for ER_LOCK_WAIT_TIMEOUT.
+	errLockWaitTimeout = 1205
+
+	// errLockDeadlock is the MySQL error number for ER_LOCK_DEADLOCK.
+	errLockDeadlock = 1213
+
+	// MySQL error numbers reported for integrity constraint violations.
+	errDupEntry              = 1062
+	errBadNull               = 1048
+	errNoDefaultForField     = 1364
+	errRowIsReferenced       = 1451
+	errNoReferencedRow       = 1452
+	errCheckConstraintFailed = 3819
+
+	// maxLimit is the largest possible row count. MySQL requires a limit when an offset is given; this
+	// value is used to express "no limit" in this case.
+	maxLimit = "18446744073709551615"
+)
+
+var (
+	// timeLayouts defines the layouts of date, datetime and timestamp values returned as text.
+	timeLayouts = []string{"2006-01-02 15:04:05.999999", "2006-01-02"}
+
+	// keyPattern extracts the key name from ER_DUP_ENTRY messages.
+	keyPattern = regexp.MustCompile(`for key '([^']+)'`)
+
+	// constraintPattern extracts the constraint name from foreign key and check constraint messages.
+	constraintPattern = regexp.MustCompile("(?:CONSTRAINT `([^`]+)`|[Cc]heck constraint '([^']+)')")
+
+	// columnPattern extracts the column name from not null messages.
+	columnPattern = regexp.MustCompile(`(?:Column|Field) '([^']+)'`)
+)
 
 // Dialect provides a MySQL dialect.
-type Dialect = depot.DefaultDialect
+type Dialect struct {
+	depot.DefaultDialect
+}
+
+var _ depot.Dialect = &Dialect{}
+
+// init registers the Dialect for github.com/go-sql-driver/mysql.
+func init() {
+	for _, name := range []string{"mysql", "*mysql.MySQLDriver"} {
+		depot.RegisterDialect(name, func() depot.Dialect { return &Dialect{} })
+	}
+}
+
+func (d *Dialect) NewClauseBuilder() depot.QueryBuilder { return depot.NewDefaultClauseBuilder(d) }
+
+// SupportsRowValues returns true as MySQL supports row value comparisons.
+func (d *Dialect) SupportsRowValues() bool { return true }
+
+// MaxParameters returns 65535 which is the maximum number of placeholders in a MySQL prepared statement.
+func (d *Dialect) MaxParameters() int { return 65535 }
+
+// IsRetryable returns true for deadlocks and lock wait timeouts.
+func (d *Dialect) IsRetryable(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+
+	return mysqlErr.Number == errLockDeadlock || mysqlErr.Number == errLockWaitTimeout
+}
+
+// ConvertValue converts the representation of time values and bits used by MySQL. The driver returns
+// date, datetime and timestamp values as byte slices unless parseTime=true is set in the DSN; these are
+// parsed as UTC. Values of bit(1) columns are converted to booleans. Booleans declared as tinyint(1) are
+// returned as integers which depot.Values.GetBool converts.
+func (d *Dialect) ConvertValue(typeName string, val interface{}) interface{} {
+	b, ok := val.([]byte)
+	if !ok {
+		return val
+	}
+
+	switch typeName {
+	case "DATE", "DATETIME", "TIMESTAMP":
+		for _, layout := range timeLayouts {
+			if t, err := time.ParseInLocation(layout, string(b), time.UTC); err == nil {
+				return t
+			}
+		}
+	case "BIT":
+		if len(b) == 1 {
+			return b[0] != 0
+		}
+	}
+
+	return val
+}
+
+// QuoteIdentifier quotes name using backticks.
+func (d *Dialect) QuoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// TranslateError translates integrity constraint violations and deadlocks into a *depot.DatabaseError. The
+// names of the violated constraint or column are extracted from the error message.
+func (d *Dialect) TranslateError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return err
+	}
+
+	dbErr := &depot.DatabaseError{Err: err}
+
+	switch mysqlErr.Number {
+	case errDupEntry:
+		dbErr.Kind = depot.ErrUniqueViolation
+		dbErr.Constraint = submatch(keyPattern, mysqlErr.Message)
+	case errRowIsReferenced, errNoReferencedRow:
+		dbErr.Kind = depot.ErrForeignKeyViolation
+		dbErr.Constraint = submatch(constraintPattern, mysqlErr.Message)
+	case errBadNull, errNoDefaultForField:
+		dbErr.Kind = depot.ErrNotNullViolation
+		dbErr.Column = submatch(columnPattern, mysqlErr.Message)
+	case errCheckConstraintFailed:
+		dbErr.Kind = depot.ErrCheckViolation
+		dbErr.Constraint = submatch(constraintPattern, mysqlErr.Message)
+	case errLockDeadlock:
+		dbErr.Kind = depot.ErrDeadlock
+	default:
+		return err
+	}
+
+	return dbErr
+}
+
+// submatch returns the first non-empty submatch of pattern in s or an empty string.
+func submatch(pattern *regexp.Regexp, s string) string {
+	matches := pattern.FindStringSubmatch(s)
+	if matches == nil {
+		return ""
+	}
+
+	for _, m := range matches[1:] {
+		if m != "" {
+			return m
+		}
+	}
+	return ""
+}
+
+// WriteLimitOffset writes limit and offset clauses. As MySQL does not support an offset without a limit,
+// the largest possible limit is written in this case.
+func (d *Dialect) WriteLimitOffset(w depot.ClauseWriter, limit, offset int, ordered bool) {
+	w.WriteString(" limit ")
+	if limit >= 0 {
+		w.BindParameter(limit)
+	} else {
+		w.WriteString(maxLimit)
+	}
+
+	if offset > 0 {
+		w.WriteString(" offset ")
+		w.BindParameter(offset)
+	}
+}
+
+// WriteUpsert writes an insert statement with an on duplicate key update clause. MySQL does not support
+// naming the conflicting columns; any primary key or unique index violation triggers the update.
+func (d *Dialect) WriteUpsert(w depot.ClauseWriter, into depot.TableClause, values depot.Values, conflictCols, updateCols []string) {
+	depot.WriteInsert(w, into, values)
+
+	w.WriteString(" on duplicate key update ")
+
+	if len(updateCols) == 0 {
+		// Assigning a column to itself leaves the existing row unchanged.
+		depot.WriteIdentifier(w, conflictCols[0])
+		w.WriteString(" = ")
+		depot.WriteIdentifier(w, conflictCols[0])
+		return
+	}
+
+	for i, col := range updateCols {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		depot.WriteIdentifier(w, col)
+		w.WriteString(" = values(")
+		depot.WriteIdentifier(w, col)
+		w.WriteRune(')')
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2021 Alexander Metzner.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/halimath/depot"
+)
+
+func TestSelect(t *testing.T) {
+	tests := map[string]struct {
+		clauses      []depot.SelectClause
+		expected     string
+		expectedArgs []interface{}
+	}{
+		"limit": {
+			clauses:      []depot.SelectClause{depot.Where(depot.Eq("order", true)), depot.OrderBy(depot.Asc("id")), depot.Limit(10)},
+			expected:     "select `id`,`text` from `audit`.`messages` where (`order` = ?) order by `id` asc limit ?",
+			expectedArgs: []interface{}{true, 10},
+		},
+		"limit and offset": {
+			clauses:      []depot.SelectClause{depot.Limit(10), depot.Offset(20)},
+			expected:     "select `id`,`text` from `audit`.`messages` limit ? offset ?",
+			expectedArgs: []interface{}{10, 20},
+		},
+		"offset": {
+			clauses:      []depot.SelectClause{depot.Offset(20)},
+			expected:     "select `id`,`text` from `audit`.`messages` limit 18446744073709551615 offset ?",
+			expectedArgs: []interface{}{20},
+		},
+		"ilike": {
+			clauses:      []depot.SelectClause{depot.Where(depot.ILike("text", "h%"))},
+			expected:     "select `id`,`text` from `audit`.`messages` where (lower(`text`) like lower(?))",
+			expectedArgs: []interface{}{"h%"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cb := (&Dialect{}).NewClauseBuilder()
+			depot.Select(depot.Cols("id", "text"), depot.From("audit.messages"), test.clauses...).Write(cb)
+
+			if cb.SQL() != test.expected {
+				t.Errorf("expected\n%s\nbut got\n%s", test.expected, cb.SQL())
+			}
+
+			if !reflect.DeepEqual(cb.Args(), test.expectedArgs) {
+				t.Errorf("got unexpected args: %v", cb.Args())
+			}
+		})
+	}
+}
+
+func TestWriteUpsert(t *testing.T) {
+	tests := map[string]struct {
+		update   []string
+		expected string
+	}{
+		"update": {
+			update:   []string{"text"},
+			expected: "insert into `messages` (`id`, `text`) values (?, ?) on duplicate key update `text` = values(`text`)",
+		},
+		"nothing": {
+			expected: "insert into `messages` (`id`, `text`) values (?, ?) on duplicate key update `id` = `id`",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := &Dialect{}
+			cb := d.NewClauseBuilder()
+			d.WriteUpsert(cb, depot.Into("messages"), depot.Values{"text": "hello", "id": "1"}, []string{"id"}, test.update)
+
+			if cb.SQL() != test.expected {
+				t.Errorf("expected\n%s\nbut got\n%s", test.expected, cb.SQL())
+			}
+
+			if !reflect.DeepEqual(cb.Args(), []interface{}{"1", "hello"}) {
+				t.Errorf("got unexpected args: %v", cb.Args())
+			}
+		})
+	}
+}
+
+func TestTranslateError(t *testing.T) {
+	tests := map[string]struct {
+		err        *mysql.MySQLError
+		kind       error
+		constraint string
+		column     string
+	}{
+		"unique": {
+			err:        &mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'messages.PRIMARY'"},
+			kind:       depot.ErrUniqueViolation,
+			constraint: "messages.PRIMARY",
+		},
+		"foreign key": {
+			err: &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key " +
+				"constraint fails (`test`.`replies`, CONSTRAINT `fk_message` FOREIGN KEY (`message_id`) " +
+				"REFERENCES `messages` (`id`))"},
+			kind:       depot.ErrForeignKeyViolation,
+			constraint: "fk_message",
+		},
+		"not null": {
+			err:    &mysql.MySQLError{Number: 1048, Message: "Column 'text' cannot be null"},
+			kind:   depot.ErrNotNullViolation,
+			column: "text",
+		},
+		"check": {
+			err:        &mysql.MySQLError{Number: 3819, Message: "Check constraint 'len_positive' is violated."},
+			kind:       depot.ErrCheckViolation,
+			constraint: "len_positive",
+		},
+		"deadlock": {
+			err:  &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"},
+			kind: depot.ErrDeadlock,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := (&Dialect{}).TranslateError(test.err)
+
+			var dbErr *depot.DatabaseError
+			if !errors.As(err, &dbErr) {
+				t.Fatalf("expected database error but got %v", err)
+			}
+
+			if !errors.Is(err, test.kind) || dbErr.Constraint != test.constraint || dbErr.Column != test.column {
+				t.Errorf("got unexpected error %#v", dbErr)
+			}
+
+			if !errors.Is(err, test.err) {
+				t.Error("expected error to unwrap to the driver error")
+			}
+		})
+	}
+
+	other := &mysql.MySQLError{Number: 1146, Message: "Table 'test.foo' doesn't exist"}
+	if err := (&Dialect{}).TranslateError(other); err != other {
+		t.Errorf("expected other errors to be returned unchanged but got %v", err)
+	}
+}
+
+func TestConvertValue(t *testing.T) {
+	tests := map[string]struct {
+		typeName string
+		val      interface{}
+		expected interface{}
+	}{
+		"datetime": {
+			typeName: "DATETIME",
+			val:      []byte("2021-10-03 17:04:05"),
+			expected: time.Date(2021, 10, 3, 17, 4, 5, 0, time.UTC),
+		},
+		"timestamp with fraction": {
+			typeName: "TIMESTAMP",
+			val:      []byte("2021-10-03 17:04:05.123456"),
+			expected: time.Date(2021, 10, 3, 17, 4, 5, 123456000, time.UTC),
+		},
+		"date": {
+			typeName: "DATE",
+			val:      []byte("2021-10-03"),
+			expected: time.Date(2021, 10, 3, 0, 0, 0, 0, time.UTC),
+		},
+		"parsed time": {
+			typeName: "DATETIME",
+			val:      time.Date(2021, 10, 3, 17, 4, 5, 0, time.UTC),
+			expected: time.Date(2021, 10, 3, 17, 4, 5, 0, time.UTC),
+		},
+		"bit": {
+			typeName: "BIT",
+			val:      []byte{1},
+			expected: true,
+		},
+		"tinyint": {
+			typeName: "TINYINT",
+			val:      int64(1),
+			expected: int64(1),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			actual := (&Dialect{}).ConvertValue(test.typeName, test.val)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected %#v but got %#v", test.expected, actual)
+			}
+		})
+	}
+
+	// Text columns are returned as is even if they look like a time value.
+	actual := (&Dialect{}).ConvertValue("VARCHAR", []byte("2021-10-03"))
+	if b, ok := actual.([]byte); !ok || string(b) != "2021-10-03" {
+		t.Errorf("expected text to be returned unchanged but got %#v", actual)
+	}
+}
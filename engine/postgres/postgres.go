@@ -15,31 +15,136 @@
 package postgres
 
 import (
+	"errors"
 	"strconv"
 	"strings"
 
 	"github.com/halimath/depot"
 )
 
-type Dialect struct{}
+// Dialect provides a PostgreSQL dialect. It uses the standard savepoint syntax provided by
+// depot.DefaultDialect.
+type Dialect struct {
+	depot.DefaultDialect
+}
+
+const (
+	// sqlStateSerializationFailure is the SQLSTATE code reported for serialization failures.
+	sqlStateSerializationFailure = "40001"
+
+	// sqlStateDeadlockDetected is the SQLSTATE code reported when a deadlock has been detected.
+	sqlStateDeadlockDetected = "40P01"
+
+	// SQLSTATE codes reported for integrity constraint violations.
+	sqlStateNotNullViolation    = "23502"
+	sqlStateForeignKeyViolation = "23503"
+	sqlStateUniqueViolation     = "23505"
+	sqlStateCheckViolation      = "23514"
+)
+
+// errorKinds maps SQLSTATE codes to the depot errors they are translated to.
+var errorKinds = map[string]error{
+	sqlStateNotNullViolation:    depot.ErrNotNullViolation,
+	sqlStateForeignKeyViolation: depot.ErrForeignKeyViolation,
+	sqlStateUniqueViolation:     depot.ErrUniqueViolation,
+	sqlStateCheckViolation:      depot.ErrCheckViolation,
+	sqlStateDeadlockDetected:    depot.ErrDeadlock,
+}
+
+// sqlStateError is implemented by the errors reported from github.com/jackc/pgx.
+type sqlStateError interface {
+	error
+	SQLState() string
+}
+
+// fieldError is implemented by the errors reported from github.com/lib/pq.
+type fieldError interface {
+	error
+	Get(field byte) string
+}
+
+// sqlState returns the SQLSTATE code reported with err or an empty string if err does not carry a code.
+func sqlState(err error) string {
+	var stateErr sqlStateError
+	if errors.As(err, &stateErr) {
+		return stateErr.SQLState()
+	}
+
+	var fieldErr fieldError
+	if errors.As(err, &fieldErr) {
+		return fieldErr.Get('C')
+	}
+
+	return ""
+}
 
 var _ depot.Dialect = &Dialect{}
 
-func (d *Dialect) NewClauseBuilder() depot.QueryBuilder { return &clauseBuilder{} }
+// init registers the Dialect for github.com/lib/pq and github.com/jackc/pgx.
+func init() {
+	for _, name := range []string{"postgres", "pgx", "*pq.Driver", "*stdlib.Driver"} {
+		depot.RegisterDialect(name, func() depot.Dialect { return &Dialect{} })
+	}
+}
+
+func (d *Dialect) NewClauseBuilder() depot.QueryBuilder { return &clauseBuilder{dialect: d} }
+
+// SupportsRowValues returns true as PostgreSQL supports row value comparisons.
+func (d *Dialect) SupportsRowValues() bool { return true }
+
+// SupportsILike returns true as PostgreSQL supports the ilike operator.
+func (d *Dialect) SupportsILike() bool { return true }
+
+// Returning returns depot.ReturningClause as PostgreSQL supports insert ... returning.
+func (d *Dialect) Returning() depot.ReturningStyle { return depot.ReturningClause }
+
+// MaxParameters returns 65535 which is the maximum number of parameters supported by the PostgreSQL wire
+// protocol.
+func (d *Dialect) MaxParameters() int { return 65535 }
+
+// TranslateError translates integrity constraint violations and deadlocks into a *depot.DatabaseError. The
+// names of the constraint and column are only available when using github.com/lib/pq.
+func (d *Dialect) TranslateError(err error) error {
+	kind, ok := errorKinds[sqlState(err)]
+	if !ok {
+		return err
+	}
+
+	dbErr := &depot.DatabaseError{
+		Kind: kind,
+		Err:  err,
+	}
+
+	var fieldErr fieldError
+	if errors.As(err, &fieldErr) {
+		dbErr.Constraint = fieldErr.Get('n')
+		dbErr.Column = fieldErr.Get('c')
+	}
+
+	return dbErr
+}
+
+// IsRetryable returns true for serialization failures and detected deadlocks.
+func (d *Dialect) IsRetryable(err error) bool {
+	state := sqlState(err)
+	return state == sqlStateSerializationFailure || state == sqlStateDeadlockDetected
+}
 
 // --
 
 type clauseBuilder struct {
-	sql  strings.Builder
-	args []interface{}
+	dialect *Dialect
+	sql     strings.Builder
+	args    []interface{}
 }
 
 var _ depot.QueryBuilder = &clauseBuilder{}
 
-func (b *clauseBuilder) WriteString(s string) { b.sql.WriteString(s) }
-func (b *clauseBuilder) WriteRune(r rune)     { b.sql.WriteRune(r) }
-func (b *clauseBuilder) SQL() string          { return b.sql.String() }
-func (b *clauseBuilder) Args() []interface{}  { return b.args }
+func (b *clauseBuilder) WriteString(s string)   { b.sql.WriteString(s) }
+func (b *clauseBuilder) WriteRune(r rune)       { b.sql.WriteRune(r) }
+func (b *clauseBuilder) SQL() string            { return b.sql.String() }
+func (b *clauseBuilder) Args() []interface{}    { return b.args }
+func (b *clauseBuilder) Dialect() depot.Dialect { return b.dialect }
 func (b *clauseBuilder) BindParameter(arg interface{}) {
 	b.args = append(b.args, arg)
 	b.sql.WriteRune('$')
@@ -0,0 +1,80 @@
+// Copyright 2021 Alexander Metzner.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/halimath/depot"
+)
+
+func TestSubqueryParameterNumbering(t *testing.T) {
+	cb := (&Dialect{}).NewClauseBuilder()
+
+	depot.Select(
+		depot.Columns(depot.Col("id"), depot.Select(depot.Cols("count(*)"), depot.From("b"), depot.Where(depot.Eq("x", 1))).As("cnt")),
+		depot.From("a"),
+		depot.Where(
+			depot.Eq("y", 2),
+			depot.InSubquery("id", depot.Select(depot.Cols("a_id"), depot.From("c"), depot.Where(depot.Eq("z", 3)))),
+			depot.Eq("w", 4),
+		),
+	).Write(cb)
+
+	expected := `select "id",(select count(*) from "b" where ("x" = $1)) as "cnt" from "a" ` +
+		`where ("y" = $2) and ("id" in (select "a_id" from "c" where ("z" = $3))) and ("w" = $4)`
+	if cb.SQL() != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, cb.SQL())
+	}
+
+	if !reflect.DeepEqual(cb.Args(), []interface{}{1, 2, 3, 4}) {
+		t.Errorf("got unexpected args: %v", cb.Args())
+	}
+}
+
+// pqError mimics the errors reported from github.com/lib/pq.
+type pqError map[byte]string
+
+func (e pqError) Error() string         { return e['M'] }
+func (e pqError) Get(field byte) string { return e[field] }
+
+func TestTranslateError(t *testing.T) {
+	err := (&Dialect{}).TranslateError(fmt.Errorf("failed: %w", pqError{
+		'C': "23505",
+		'M': "duplicate key value violates unique constraint",
+		'n': "messages_pkey",
+	}))
+
+	if !errors.Is(err, depot.ErrUniqueViolation) {
+		t.Fatalf("expected unique violation but got %v", err)
+	}
+
+	var dbErr *depot.DatabaseError
+	if !errors.As(err, &dbErr) || dbErr.Constraint != "messages_pkey" {
+		t.Errorf("expected constraint name to be set but got %#v", err)
+	}
+
+	if !(&Dialect{}).IsRetryable((&Dialect{}).TranslateError(pqError{'C': "40P01"})) {
+		t.Error("expected translated deadlock to be retryable")
+	}
+
+	other := pqError{'C': "42P01"}
+	if translated := (&Dialect{}).TranslateError(other); translated == nil || errors.Is(translated, depot.ErrDeadlock) {
+		t.Errorf("expected other errors to be returned unchanged but got %v", translated)
+	}
+}
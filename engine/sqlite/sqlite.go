@@ -15,10 +15,162 @@
 package sqlite
 
 import (
-	"github.com/halimath/depot"
+	"errors"
+	"strings"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/halimath/depot"
+	"github.com/mattn/go-sqlite3"
 )
 
 // Dialect provides a dialect for the SQLite3 database.
-type Dialect = depot.DefaultDialect
+type Dialect struct {
+	depot.DefaultDialect
+
+	// UseReturning enables the use of insert ... returning to read generated values. This requires SQLite
+	// 3.35 or later. If not set, the last insert id is used.
+	UseReturning bool
+
+	// ParameterLimit overrides the maximum number of parameters bound to a single statement. It defaults to
+	// 32766 which is the limit of SQLite 3.32 and later. Set it to 999 when using an older version.
+	ParameterLimit int
+}
+
+// defaultParameterLimit is the default value of SQLITE_MAX_VARIABLE_NUMBER since SQLite 3.32.
+const defaultParameterLimit = 32766
+
+var _ depot.Dialect = &Dialect{}
+
+// init registers the Dialect for github.com/mattn/go-sqlite3.
+func init() {
+	for _, name := range []string{"sqlite3", "*sqlite3.SQLiteDriver"} {
+		depot.RegisterDialect(name, func() depot.Dialect { return &Dialect{} })
+	}
+}
+
+func (d *Dialect) NewClauseBuilder() depot.QueryBuilder { return depot.NewDefaultClauseBuilder(d) }
+
+// SupportsRowValues returns true as SQLite supports row value comparisons.
+func (d *Dialect) SupportsRowValues() bool { return true }
+
+// Returning returns depot.ReturningClause if UseReturning is set and depot.LastInsertID otherwise.
+func (d *Dialect) Returning() depot.ReturningStyle {
+	if d.UseReturning {
+		return depot.ReturningClause
+	}
+	return depot.LastInsertID
+}
+
+// MaxParameters returns ParameterLimit if set and 32766 otherwise.
+func (d *Dialect) MaxParameters() int {
+	if d.ParameterLimit > 0 {
+		return d.ParameterLimit
+	}
+	return defaultParameterLimit
+}
+
+// TranslateError translates constraint violations into a *depot.DatabaseError. The names of the violated
+// column or check constraint are extracted from the error message.
+func (d *Dialect) TranslateError(err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.Code != sqlite3.ErrConstraint {
+		return err
+	}
+
+	dbErr := &depot.DatabaseError{Err: err}
+	detail := constraintDetail(sqliteErr.Error())
+
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		dbErr.Kind = depot.ErrUniqueViolation
+		dbErr.Column = columnName(detail)
+	case sqlite3.ErrConstraintForeignKey:
+		dbErr.Kind = depot.ErrForeignKeyViolation
+	case sqlite3.ErrConstraintNotNull:
+		dbErr.Kind = depot.ErrNotNullViolation
+		dbErr.Column = columnName(detail)
+	case sqlite3.ErrConstraintCheck:
+		dbErr.Kind = depot.ErrCheckViolation
+		dbErr.Constraint = detail
+	default:
+		return err
+	}
+
+	return dbErr
+}
+
+// constraintDetail returns the part of a constraint error message following the colon, such as
+// "messages.id" for "UNIQUE constraint failed: messages.id".
+func constraintDetail(msg string) string {
+	i := strings.Index(msg, ": ")
+	if i < 0 {
+		return ""
+	}
+	return msg[i+2:]
+}
+
+// columnName returns the column name from a table qualified column name given in detail. It returns an
+// empty string if detail names multiple columns.
+func columnName(detail string) string {
+	if detail == "" || strings.Contains(detail, ",") {
+		return ""
+	}
+	return detail[strings.LastIndex(detail, ".")+1:]
+}
+
+// ConvertValue converts the representation of time values and booleans used by SQLite. SQLite stores time
+// values as text and booleans as integers. The driver only converts columns declared exactly as date,
+// datetime, timestamp or boolean. ConvertValue also parses text values of columns declared with any other
+// date or time type, such as timestamp(3), using the driver's formats and converts integers of columns
+// declared as bool.
+func (d *Dialect) ConvertValue(typeName string, val interface{}) interface{} {
+	typeName = strings.ToLower(typeName)
+
+	switch v := val.(type) {
+	case string:
+		if strings.Contains(typeName, "date") || strings.Contains(typeName, "time") {
+			if t, ok := parseTime(v); ok {
+				return t
+			}
+		}
+	case int64:
+		if strings.HasPrefix(typeName, "bool") {
+			return v != 0
+		}
+	}
+
+	return val
+}
+
+// parseTime parses s using the formats used by the driver to store time values.
+func parseTime(s string) (time.Time, bool) {
+	s = strings.TrimSuffix(s, "Z")
+	for _, format := range sqlite3.SQLiteTimestampFormats {
+		if t, err := time.ParseInLocation(format, s, time.UTC); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// IsRetryable returns true if the database file or a table is locked by another connection.
+func (d *Dialect) IsRetryable(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+}
+
+// WriteLimitOffset writes limit and offset clauses. As SQLite does not support an offset without a limit,
+// a limit of -1 (meaning no limit) is written in this case.
+func (d *Dialect) WriteLimitOffset(w depot.ClauseWriter, limit, offset int, ordered bool) {
+	w.WriteString(" limit ")
+	w.BindParameter(limit)
+
+	if offset > 0 {
+		w.WriteString(" offset ")
+		w.BindParameter(offset)
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2021 Alexander Metzner.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlite
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/halimath/depot"
+)
+
+func TestSelect(t *testing.T) {
+	tests := map[string]struct {
+		clauses      []depot.SelectClause
+		expected     string
+		expectedArgs []interface{}
+	}{
+		"limit": {
+			clauses:      []depot.SelectClause{depot.Where(depot.Eq("order", true)), depot.OrderBy(depot.Asc("id")), depot.Limit(10)},
+			expected:     `select "id","text" from "messages" where ("order" = ?) order by "id" asc limit ?`,
+			expectedArgs: []interface{}{true, 10},
+		},
+		"limit and offset": {
+			clauses:      []depot.SelectClause{depot.Limit(10), depot.Offset(20)},
+			expected:     `select "id","text" from "messages" limit ? offset ?`,
+			expectedArgs: []interface{}{10, 20},
+		},
+		"offset": {
+			clauses:      []depot.SelectClause{depot.Offset(20)},
+			expected:     `select "id","text" from "messages" limit ? offset ?`,
+			expectedArgs: []interface{}{-1, 20},
+		},
+		"ilike": {
+			clauses:      []depot.SelectClause{depot.Where(depot.ILike("text", "h%"))},
+			expected:     `select "id","text" from "messages" where (lower("text") like lower(?))`,
+			expectedArgs: []interface{}{"h%"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cb := (&Dialect{}).NewClauseBuilder()
+			depot.Select(depot.Cols("id", "text"), depot.From("messages"), test.clauses...).Write(cb)
+
+			if cb.SQL() != test.expected {
+				t.Errorf("expected\n%s\nbut got\n%s", test.expected, cb.SQL())
+			}
+
+			if !reflect.DeepEqual(cb.Args(), test.expectedArgs) {
+				t.Errorf("got unexpected args: %v", cb.Args())
+			}
+		})
+	}
+}
+
+func TestWriteUpsert(t *testing.T) {
+	tests := map[string]struct {
+		update   []string
+		expected string
+	}{
+		"update": {
+			update:   []string{"text"},
+			expected: `insert into "messages" ("id", "text") values (?, ?) on conflict ("id") do update set "text" = excluded."text"`,
+		},
+		"nothing": {
+			expected: `insert into "messages" ("id", "text") values (?, ?) on conflict ("id") do nothing`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := &Dialect{}
+			cb := d.NewClauseBuilder()
+			d.WriteUpsert(cb, depot.Into("messages"), depot.Values{"text": "hello", "id": "1"}, []string{"id"}, test.update)
+
+			if cb.SQL() != test.expected {
+				t.Errorf("expected\n%s\nbut got\n%s", test.expected, cb.SQL())
+			}
+
+			if !reflect.DeepEqual(cb.Args(), []interface{}{"1", "hello"}) {
+				t.Errorf("got unexpected args: %v", cb.Args())
+			}
+		})
+	}
+}
+
+func TestConvertValue(t *testing.T) {
+	tests := map[string]struct {
+		typeName string
+		val      interface{}
+		expected interface{}
+	}{
+		"timestamp with precision": {
+			typeName: "TIMESTAMP(3)",
+			val:      "2021-10-03 17:04:05.123+02:00",
+			expected: time.Date(2021, 10, 3, 17, 4, 5, 123000000, time.FixedZone("", 2*60*60)),
+		},
+		"datetime without zone": {
+			typeName: "DATETIME2",
+			val:      "2021-10-03T17:04:05Z",
+			expected: time.Date(2021, 10, 3, 17, 4, 5, 0, time.UTC),
+		},
+		"date": {
+			typeName: "DATE_ONLY",
+			val:      "2021-10-03",
+			expected: time.Date(2021, 10, 3, 0, 0, 0, 0, time.UTC),
+		},
+		"invalid time": {
+			typeName: "TIMESTAMP(3)",
+			val:      "yesterday",
+			expected: "yesterday",
+		},
+		"text": {
+			typeName: "TEXT",
+			val:      "2021-10-03",
+			expected: "2021-10-03",
+		},
+		"bool": {
+			typeName: "BOOL",
+			val:      int64(1),
+			expected: true,
+		},
+		"integer": {
+			typeName: "INTEGER",
+			val:      int64(1),
+			expected: int64(1),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			actual := (&Dialect{}).ConvertValue(test.typeName, test.val)
+
+			if at, ok := actual.(time.Time); ok {
+				if et, ok := test.expected.(time.Time); !ok || !at.Equal(et) {
+					t.Errorf("expected %v but got %v", test.expected, actual)
+				}
+				return
+			}
+
+			if actual != test.expected {
+				t.Errorf("expected %#v but got %#v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReadConvertedValues(t *testing.T) {
+	db, err := depot.Open("sqlite3", ":memory:", depot.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tx, _, err := db.BeginTx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("create table events (id integer primary key, occurred timestamp(3), done bool, note text)"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = tx.Exec("insert into events values (1, '2021-10-03 17:04:05.123', 1, '2021-10-03')")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vals, err := tx.QueryOne(depot.Cols("occurred", "done", "note"), depot.From("events"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if occurred, ok := vals.GetTime("occurred"); !ok || !occurred.Equal(time.Date(2021, 10, 3, 17, 4, 5, 123000000, time.UTC)) {
+		t.Errorf("expected occurred to be read as time but got %#v", vals["occurred"])
+	}
+
+	if done, ok := vals.GetBool("done"); !ok || !done {
+		t.Errorf("expected done to be read as bool but got %#v", vals["done"])
+	}
+
+	if note, ok := vals.GetString("note"); !ok || note != "2021-10-03" {
+		t.Errorf("expected note to be read as string but got %#v", vals["note"])
+	}
+}
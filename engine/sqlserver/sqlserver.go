@@ -0,0 +1,274 @@
+// Copyright 2021 Alexander Metzner.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlserver
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/halimath/depot"
+)
+
+const (
+	// SQL Server error numbers reported for integrity constraint violations.
+	errDuplicateKey       = 2627
+	errDuplicateKeyIndex  = 2601
+	errConstraintConflict = 547
+	errNullNotAllowed     = 515
+
+	// errDeadlockVictim is the SQL Server error number reported when a transaction was chosen as a
+	// deadlock victim.
+	errDeadlockVictim = 1205
+
+	// errLockTimeout is the SQL Server error number reported when a lock request timed out.
+	errLockTimeout = 1222
+
+	// maxParameters is the maximum number of parameters supported by a single SQL Server request.
+	maxParameters = 2100
+
+	// maxRows is the maximum number of rows in the values clause of a single insert statement.
+	maxRows = 1000
+)
+
+var (
+	// constraintPattern extracts the constraint name from constraint violation messages.
+	constraintPattern = regexp.MustCompile(`constraint ['"]([^'"]+)['"]`)
+
+	// indexPattern extracts the index name from duplicate key messages reported for unique indexes.
+	indexPattern = regexp.MustCompile(`unique index '([^']+)'`)
+
+	// columnPattern extracts the column name from not null messages.
+	columnPattern = regexp.MustCompile(`column '([^']+)'`)
+)
+
+// numberedError is implemented by the errors reported from github.com/denisenkom/go-mssqldb.
+type numberedError interface {
+	error
+	SQLErrorNumber() int32
+}
+
+// errorNumber returns the SQL Server error number reported with err or 0 if err does not carry a number.
+func errorNumber(err error) int32 {
+	var numErr numberedError
+	if errors.As(err, &numErr) {
+		return numErr.SQLErrorNumber()
+	}
+	return 0
+}
+
+// Dialect provides a Microsoft SQL Server dialect. Parameters are written as @p1, @p2, ... and identifiers
+// are quoted using brackets.
+type Dialect struct {
+	depot.DefaultDialect
+}
+
+var _ depot.Dialect = &Dialect{}
+
+// init registers the Dialect for github.com/denisenkom/go-mssqldb.
+func init() {
+	for _, name := range []string{"sqlserver", "mssql", "*mssql.Driver"} {
+		depot.RegisterDialect(name, func() depot.Dialect { return &Dialect{} })
+	}
+}
+
+func (d *Dialect) NewClauseBuilder() depot.QueryBuilder { return &clauseBuilder{dialect: d} }
+
+// Savepoint returns the statement creating a savepoint.
+func (d *Dialect) Savepoint(name string) string {
+	return "save transaction " + name
+}
+
+// RollbackToSavepoint returns the statement rolling back to a savepoint.
+func (d *Dialect) RollbackToSavepoint(name string) string {
+	return "rollback transaction " + name
+}
+
+// ReleaseSavepoint returns an empty string as SQL Server does not support releasing savepoints.
+func (d *Dialect) ReleaseSavepoint(name string) string {
+	return ""
+}
+
+// QuoteIdentifier quotes name using brackets.
+func (d *Dialect) QuoteIdentifier(name string) string {
+	return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+}
+
+// Returning returns depot.OutputClause as SQL Server supports insert ... output.
+func (d *Dialect) Returning() depot.ReturningStyle { return depot.OutputClause }
+
+// MaxParameters returns 2100 which is the maximum number of parameters supported by a single request.
+func (d *Dialect) MaxParameters() int { return maxParameters }
+
+// MaxRows returns 1000 which is the maximum number of rows in the values clause of an insert statement.
+func (d *Dialect) MaxRows() int { return maxRows }
+
+// WriteLimitOffset writes offset and fetch clauses. As SQL Server requires an order by clause for paging,
+// an order by (select null) clause is written for unordered queries.
+func (d *Dialect) WriteLimitOffset(w depot.ClauseWriter, limit, offset int, ordered bool) {
+	if !ordered {
+		w.WriteString(" order by (select null)")
+	}
+
+	w.WriteString(" offset ")
+	w.BindParameter(offset)
+	w.WriteString(" rows")
+
+	if limit >= 0 {
+		w.WriteString(" fetch next ")
+		w.BindParameter(limit)
+		w.WriteString(" rows only")
+	}
+}
+
+// WriteUpsert writes a merge statement inserting values if no row matches the conflictCols and updating
+// updateCols of the matching row otherwise. The merge statement holds a lock on the matched range to
+// prevent concurrent inserts of the same key.
+func (d *Dialect) WriteUpsert(w depot.ClauseWriter, into depot.TableClause, values depot.Values, conflictCols, updateCols []string) {
+	cols := make([]string, 0, len(values))
+	for col := range values {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+
+	w.WriteString("merge into ")
+	into.Write(w)
+	w.WriteString(" with (holdlock) as target using (values (")
+	for i, col := range cols {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		depot.WriteValue(w, values[col])
+	}
+	w.WriteString(")) as source (")
+	writeColumns(w, "", cols)
+	w.WriteString(") on ")
+
+	for i, col := range conflictCols {
+		if i > 0 {
+			w.WriteString(" and ")
+		}
+		w.WriteString("target.")
+		depot.WriteIdentifier(w, col)
+		w.WriteString(" = source.")
+		depot.WriteIdentifier(w, col)
+	}
+
+	if len(updateCols) > 0 {
+		w.WriteString(" when matched then update set ")
+		for i, col := range updateCols {
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			w.WriteString("target.")
+			depot.WriteIdentifier(w, col)
+			w.WriteString(" = source.")
+			depot.WriteIdentifier(w, col)
+		}
+	}
+
+	w.WriteString(" when not matched then insert (")
+	writeColumns(w, "", cols)
+	w.WriteString(") values (")
+	writeColumns(w, "source.", cols)
+	w.WriteString(");")
+}
+
+// writeColumns writes the comma separated list of cols each prefixed with prefix.
+func writeColumns(w depot.ClauseWriter, prefix string, cols []string) {
+	for i, col := range cols {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		w.WriteString(prefix)
+		depot.WriteIdentifier(w, col)
+	}
+}
+
+// TranslateError translates integrity constraint violations and deadlocks into a *depot.DatabaseError. The
+// names of the violated constraint or column are extracted from the error message.
+func (d *Dialect) TranslateError(err error) error {
+	number := errorNumber(err)
+	if number == 0 {
+		return err
+	}
+
+	dbErr := &depot.DatabaseError{Err: err}
+	msg := err.Error()
+
+	switch number {
+	case errDuplicateKey:
+		dbErr.Kind = depot.ErrUniqueViolation
+		dbErr.Constraint = submatch(constraintPattern, msg)
+	case errDuplicateKeyIndex:
+		dbErr.Kind = depot.ErrUniqueViolation
+		dbErr.Constraint = submatch(indexPattern, msg)
+	case errConstraintConflict:
+		// SQL Server reports the same error number for foreign key and check constraint violations.
+		if strings.Contains(msg, "CHECK constraint") {
+			dbErr.Kind = depot.ErrCheckViolation
+		} else {
+			dbErr.Kind = depot.ErrForeignKeyViolation
+		}
+		dbErr.Constraint = submatch(constraintPattern, msg)
+	case errNullNotAllowed:
+		dbErr.Kind = depot.ErrNotNullViolation
+		dbErr.Column = submatch(columnPattern, msg)
+	case errDeadlockVictim:
+		dbErr.Kind = depot.ErrDeadlock
+	default:
+		return err
+	}
+
+	return dbErr
+}
+
+// submatch returns the first submatch of pattern in s or an empty string.
+func submatch(pattern *regexp.Regexp, s string) string {
+	matches := pattern.FindStringSubmatch(s)
+	if matches == nil {
+		return ""
+	}
+	return matches[1]
+}
+
+// IsRetryable returns true for deadlocks and lock timeouts.
+func (d *Dialect) IsRetryable(err error) bool {
+	number := errorNumber(err)
+	return number == errDeadlockVictim || number == errLockTimeout
+}
+
+// --
+
+type clauseBuilder struct {
+	dialect *Dialect
+	sql     strings.Builder
+	args    []interface{}
+}
+
+var _ depot.QueryBuilder = &clauseBuilder{}
+
+func (b *clauseBuilder) WriteString(s string)   { b.sql.WriteString(s) }
+func (b *clauseBuilder) WriteRune(r rune)       { b.sql.WriteRune(r) }
+func (b *clauseBuilder) SQL() string            { return b.sql.String() }
+func (b *clauseBuilder) Args() []interface{}    { return b.args }
+func (b *clauseBuilder) Dialect() depot.Dialect { return b.dialect }
+func (b *clauseBuilder) BindParameter(arg interface{}) {
+	b.args = append(b.args, arg)
+	b.sql.WriteString("@p")
+	b.sql.WriteString(strconv.Itoa(len(b.args)))
+}
@@ -0,0 +1,242 @@
+// Copyright 2021 Alexander Metzner.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlserver
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/halimath/depot"
+)
+
+func TestSelect(t *testing.T) {
+	tests := map[string]struct {
+		clauses      []depot.SelectClause
+		expected     string
+		expectedArgs []interface{}
+	}{
+		"where": {
+			clauses:      []depot.SelectClause{depot.Where(depot.Eq("order", 1), depot.ILike("text", "h%"))},
+			expected:     "select [id],[text] from [audit].[messages] where ([order] = @p1) and (lower([text]) like lower(@p2))",
+			expectedArgs: []interface{}{1, "h%"},
+		},
+		"limit": {
+			clauses:      []depot.SelectClause{depot.OrderBy(depot.Asc("id")), depot.Limit(10)},
+			expected:     "select [id],[text] from [audit].[messages] order by [id] asc offset @p1 rows fetch next @p2 rows only",
+			expectedArgs: []interface{}{0, 10},
+		},
+		"limit and offset": {
+			clauses:      []depot.SelectClause{depot.Where(depot.Eq("id", 1)), depot.OrderBy(depot.Desc("id")), depot.Limit(10), depot.Offset(20)},
+			expected:     "select [id],[text] from [audit].[messages] where ([id] = @p1) order by [id] desc offset @p2 rows fetch next @p3 rows only",
+			expectedArgs: []interface{}{1, 20, 10},
+		},
+		"limit 0": {
+			clauses:      []depot.SelectClause{depot.Where(depot.Eq("id", 1)), depot.OrderBy(depot.Asc("id")), depot.Limit(0), depot.Offset(20)},
+			expected:     "select [id],[text] from [audit].[messages] where ([id] = @p1) and 1 = 0 order by [id] asc",
+			expectedArgs: []interface{}{1},
+		},
+		"unordered offset": {
+			clauses:      []depot.SelectClause{depot.Offset(20)},
+			expected:     "select [id],[text] from [audit].[messages] order by (select null) offset @p1 rows",
+			expectedArgs: []interface{}{20},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cb := (&Dialect{}).NewClauseBuilder()
+			depot.Select(depot.Cols("id", "text"), depot.From("audit.messages"), test.clauses...).Write(cb)
+
+			if cb.SQL() != test.expected {
+				t.Errorf("expected\n%s\nbut got\n%s", test.expected, cb.SQL())
+			}
+
+			if !reflect.DeepEqual(cb.Args(), test.expectedArgs) {
+				t.Errorf("got unexpected args: %v", cb.Args())
+			}
+		})
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	if actual := (&Dialect{}).QuoteIdentifier("a]b"); actual != "[a]]b]" {
+		t.Errorf("expected closing bracket to be escaped but got %s", actual)
+	}
+}
+
+func TestWriteInsertReturning(t *testing.T) {
+	cb := (&Dialect{}).NewClauseBuilder()
+	depot.WriteInsertReturning(cb, depot.Into("messages"), depot.Values{"text": "hello", "len": 5}, depot.Cols("id", "created"))
+
+	expected := "insert into [messages] ([len], [text]) output inserted.[id], inserted.[created] values (@p1, @p2)"
+	if cb.SQL() != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, cb.SQL())
+	}
+
+	if !reflect.DeepEqual(cb.Args(), []interface{}{5, "hello"}) {
+		t.Errorf("got unexpected args: %v", cb.Args())
+	}
+}
+
+func TestBuildInsertMany(t *testing.T) {
+	rows := make([]depot.Values, 2500)
+	for i := range rows {
+		rows[i] = depot.Values{"id": i}
+	}
+
+	statements, err := depot.BuildInsertMany(&Dialect{}, depot.Into("messages"), rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedRows := []int{1000, 1000, 500}
+	if len(statements) != len(expectedRows) {
+		t.Fatalf("expected %d statements but got %d", len(expectedRows), len(statements))
+	}
+
+	next := 0
+	for i, cb := range statements {
+		values := make([]string, expectedRows[i])
+		expectedArgs := make([]interface{}, expectedRows[i])
+		for j := range values {
+			values[j] = fmt.Sprintf("(@p%d)", j+1)
+			expectedArgs[j] = next
+			next++
+		}
+
+		expected := "insert into [messages] ([id]) values " + strings.Join(values, ", ")
+		if cb.SQL() != expected {
+			t.Errorf("statement %d: expected\n%s\nbut got\n%s", i, expected, cb.SQL())
+		}
+
+		if !reflect.DeepEqual(cb.Args(), expectedArgs) {
+			t.Errorf("statement %d: got unexpected args: %v", i, cb.Args())
+		}
+	}
+}
+
+func TestWriteUpsert(t *testing.T) {
+	tests := map[string]struct {
+		update   []string
+		expected string
+	}{
+		"update": {
+			update: []string{"text"},
+			expected: "merge into [messages] with (holdlock) as target using (values (@p1, @p2)) as source ([id], [text]) " +
+				"on target.[id] = source.[id] when matched then update set target.[text] = source.[text] " +
+				"when not matched then insert ([id], [text]) values (source.[id], source.[text]);",
+		},
+		"nothing": {
+			expected: "merge into [messages] with (holdlock) as target using (values (@p1, @p2)) as source ([id], [text]) " +
+				"on target.[id] = source.[id] when not matched then insert ([id], [text]) values (source.[id], source.[text]);",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := &Dialect{}
+			cb := d.NewClauseBuilder()
+			d.WriteUpsert(cb, depot.Into("messages"), depot.Values{"text": "hello", "id": "1"}, []string{"id"}, test.update)
+
+			if cb.SQL() != test.expected {
+				t.Errorf("expected\n%s\nbut got\n%s", test.expected, cb.SQL())
+			}
+
+			if !reflect.DeepEqual(cb.Args(), []interface{}{"1", "hello"}) {
+				t.Errorf("got unexpected args: %v", cb.Args())
+			}
+		})
+	}
+}
+
+// mssqlError mimics the errors reported from github.com/denisenkom/go-mssqldb.
+type mssqlError struct {
+	number  int32
+	message string
+}
+
+func (e mssqlError) Error() string         { return "mssql: " + e.message }
+func (e mssqlError) SQLErrorNumber() int32 { return e.number }
+
+func TestTranslateError(t *testing.T) {
+	tests := map[string]struct {
+		err        mssqlError
+		kind       error
+		constraint string
+		column     string
+	}{
+		"primary key": {
+			err: mssqlError{2627, "Violation of PRIMARY KEY constraint 'PK_messages'. " +
+				"Cannot insert duplicate key in object 'dbo.messages'. The duplicate key value is (1)."},
+			kind:       depot.ErrUniqueViolation,
+			constraint: "PK_messages",
+		},
+		"unique index": {
+			err: mssqlError{2601, "Cannot insert duplicate key row in object 'dbo.messages' with unique index 'ix_text'. " +
+				"The duplicate key value is (hello)."},
+			kind:       depot.ErrUniqueViolation,
+			constraint: "ix_text",
+		},
+		"foreign key": {
+			err: mssqlError{547, `The INSERT statement conflicted with the FOREIGN KEY constraint "fk_user". ` +
+				`The conflict occurred in database "test", table "dbo.users", column 'id'.`},
+			kind:       depot.ErrForeignKeyViolation,
+			constraint: "fk_user",
+		},
+		"check": {
+			err: mssqlError{547, `The INSERT statement conflicted with the CHECK constraint "len_positive". ` +
+				`The conflict occurred in database "test", table "dbo.messages", column 'len'.`},
+			kind:       depot.ErrCheckViolation,
+			constraint: "len_positive",
+		},
+		"not null": {
+			err: mssqlError{515, "Cannot insert the value NULL into column 'text', table 'test.dbo.messages'; " +
+				"column does not allow nulls. INSERT fails."},
+			kind:   depot.ErrNotNullViolation,
+			column: "text",
+		},
+		"deadlock": {
+			err:  mssqlError{1205, "Transaction (Process ID 52) was deadlocked on lock resources with another process."},
+			kind: depot.ErrDeadlock,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := (&Dialect{}).TranslateError(fmt.Errorf("failed: %w", test.err))
+
+			var dbErr *depot.DatabaseError
+			if !errors.As(err, &dbErr) {
+				t.Fatalf("expected database error but got %v", err)
+			}
+
+			if dbErr.Kind != test.kind || dbErr.Constraint != test.constraint || dbErr.Column != test.column {
+				t.Errorf("got unexpected error %#v", dbErr)
+			}
+		})
+	}
+
+	if !(&Dialect{}).IsRetryable((&Dialect{}).TranslateError(mssqlError{1205, "deadlock"})) {
+		t.Error("expected translated deadlock to be retryable")
+	}
+
+	other := mssqlError{208, "Invalid object name 'foo'."}
+	if err := (&Dialect{}).TranslateError(other); err != other {
+		t.Errorf("expected other errors to be returned unchanged but got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 Alexander Metzner.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depot
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrUniqueViolation is reported when a statement violates a primary key or unique constraint.
+	ErrUniqueViolation = errors.New("unique violation")
+
+	// ErrForeignKeyViolation is reported when a statement violates a foreign key constraint.
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+
+	// ErrNotNullViolation is reported when a statement stores null in a column that does not allow null.
+	ErrNotNullViolation = errors.New("not null violation")
+
+	// ErrCheckViolation is reported when a statement violates a check constraint.
+	ErrCheckViolation = errors.New("check violation")
+
+	// ErrDeadlock is reported when the database aborted a statement to resolve a deadlock.
+	ErrDeadlock = errors.New("deadlock")
+)
+
+// DatabaseError is returned for errors reported by the database that have been classified by the Dialect.
+// Use errors.Is with one of the sentinel errors, such as ErrUniqueViolation, to check the kind of error.
+// DatabaseError unwraps to the error reported by the driver.
+type DatabaseError struct {
+	// Kind is the sentinel error describing the kind of error.
+	Kind error
+
+	// Constraint contains the name of the violated constraint if reported by the database.
+	Constraint string
+
+	// Column contains the name of the column causing the error if reported by the database.
+	Column string
+
+	// Err is the error reported by the driver.
+	Err error
+}
+
+func (e *DatabaseError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Kind.Error())
+
+	if e.Constraint != "" {
+		b.WriteString(" on constraint ")
+		b.WriteString(e.Constraint)
+	}
+
+	if e.Column != "" {
+		b.WriteString(" on column ")
+		b.WriteString(e.Column)
+	}
+
+	b.WriteString(": ")
+	b.WriteString(e.Err.Error())
+
+	return b.String()
+}
+
+// Is reports whether target is the sentinel error describing the kind of e.
+func (e *DatabaseError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap returns the error reported by the driver.
+func (e *DatabaseError) Unwrap() error {
+	return e.Err
+}
@@ -18,6 +18,59 @@ var (
 	messageRepoTable = depot.Table("messages")
 )
 
+// Errors returned from MessageRepo. Use errors.Is to check for them. Each error also matches the
+// depot error it is based on.
+var (
+	// ErrMessageNotFound is returned when no Message matches the query.
+	ErrMessageNotFound = &messageRepoError{msg: "message not found", err: depot.ErrNoResult}
+
+	// ErrMessageDuplicate is returned when storing a Message violates a unique constraint.
+	ErrMessageDuplicate = &messageRepoError{msg: "duplicate message", err: depot.ErrUniqueViolation}
+
+	// ErrMessageForeignKeyViolation is returned when storing a Message violates a foreign key constraint.
+	ErrMessageForeignKeyViolation = &messageRepoError{msg: "message violates a foreign key constraint", err: depot.ErrForeignKeyViolation}
+
+	// ErrMessageNotNullViolation is returned when storing a Message with null in a not null column.
+	ErrMessageNotNullViolation = &messageRepoError{msg: "message violates a not null constraint", err: depot.ErrNotNullViolation}
+
+	// ErrMessageCheckViolation is returned when storing a Message violates a check constraint.
+	ErrMessageCheckViolation = &messageRepoError{msg: "message violates a check constraint", err: depot.ErrCheckViolation}
+
+	// ErrMessageDeadlock is returned when the database aborted a statement accessing Message to resolve a deadlock.
+	ErrMessageDeadlock = &messageRepoError{msg: "deadlock accessing message", err: depot.ErrDeadlock}
+)
+
+// messageRepoErrors lists the errors returned from MessageRepo.
+var messageRepoErrors = []*messageRepoError{
+	ErrMessageNotFound,
+	ErrMessageDuplicate,
+	ErrMessageForeignKeyViolation,
+	ErrMessageNotNullViolation,
+	ErrMessageCheckViolation,
+	ErrMessageDeadlock,
+}
+
+// messageRepoError is the type of the errors returned from MessageRepo. The exported
+// errors unwrap to the depot error they are based on. Errors returned from the methods match the exported
+// error of their kind using errors.Is and unwrap to the error reported by depot.
+type messageRepoError struct {
+	kind *messageRepoError
+	msg  string
+	err  error
+}
+
+func (e *messageRepoError) Error() string {
+	return e.msg
+}
+
+func (e *messageRepoError) Is(target error) bool {
+	return e.kind != nil && target == error(e.kind)
+}
+
+func (e *messageRepoError) Unwrap() error {
+	return e.err
+}
+
 type MessageRepo struct {
 	db *depot.DB
 }
@@ -29,7 +82,7 @@ func (r *MessageRepo) Begin(ctx context.Context) (context.Context, error) {
 
 func (r *MessageRepo) Commit(ctx context.Context) error {
 	tx := depot.MustGetTx(ctx)
-	return tx.Commit()
+	return r.wrapError(tx.Commit())
 }
 
 func (r *MessageRepo) Rollback(ctx context.Context) error {
@@ -37,6 +90,17 @@ func (r *MessageRepo) Rollback(ctx context.Context) error {
 	return tx.Rollback()
 }
 
+// wrapError wraps err so that it matches the error returned from MessageRepo that corresponds to the
+// depot error contained in err. Other errors are returned unchanged.
+func (r *MessageRepo) wrapError(err error) error {
+	for _, kind := range messageRepoErrors {
+		if errors.Is(err, kind.err) {
+			return &messageRepoError{kind: kind, msg: err.Error(), err: err}
+		}
+	}
+	return err
+}
+
 func (r *MessageRepo) fromValues(vals depot.Values) (*models.Message, error) {
 	var ok bool
 
@@ -117,7 +181,7 @@ func (r *MessageRepo) find(ctx context.Context, clauses ...depot.SelectClause) (
 	tx := depot.MustGetTx(ctx)
 	vals, err := tx.QueryMany(messageRepoCols, messageRepoTable, clauses...)
 	if err != nil {
-		err = fmt.Errorf("failed to load models.Message: %w", err)
+		err = r.wrapError(fmt.Errorf("failed to load models.Message: %w", err))
 		tx.Error(err)
 		return nil, err
 	}
@@ -133,11 +197,42 @@ func (r *MessageRepo) find(ctx context.Context, clauses ...depot.SelectClause) (
 	return res, nil
 }
 
+// findEach streams the models.Messages matching clauses to fn one at a time. The rows are read while fn
+// is called, so fn must not use the transaction bound to ctx, i.e. it must not call other methods of the
+// repository. Use find to load all entities first if fn needs to access the database.
+func (r *MessageRepo) findEach(ctx context.Context, fn func(*models.Message) error, clauses ...depot.SelectClause) error {
+	tx := depot.MustGetTx(ctx)
+	cursor, err := tx.QueryIter(messageRepoCols, messageRepoTable, clauses...)
+	if err != nil {
+		err = r.wrapError(fmt.Errorf("failed to load models.Message: %w", err))
+		tx.Error(err)
+		return err
+	}
+	defer cursor.Close()
+
+	for cursor.Next() {
+		entity, err := r.fromValues(cursor.Values())
+		if err != nil {
+			return err
+		}
+		if err := fn(entity); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		err = r.wrapError(fmt.Errorf("failed to load models.Message: %w", err))
+		tx.Error(err)
+		return err
+	}
+	return nil
+}
+
 func (r *MessageRepo) count(ctx context.Context, clauses ...depot.WhereClause) (int, error) {
 	tx := depot.MustGetTx(ctx)
 	count, err := tx.QueryCount(messageRepoTable, clauses...)
 	if err != nil {
-		err = fmt.Errorf("failed to count models.Message: %w", err)
+		err = r.wrapError(fmt.Errorf("failed to count models.Message: %w", err))
 		tx.Error(err)
 		return 0, err
 	}
@@ -145,11 +240,22 @@ func (r *MessageRepo) count(ctx context.Context, clauses ...depot.WhereClause) (
 	return count, err
 }
 
+func (r *MessageRepo) FindAll(ctx context.Context, offset, limit int) ([]*models.Message, error) {
+	clauses := []depot.SelectClause{depot.OrderBy(depot.Asc("id"))}
+	if offset > 0 {
+		clauses = append(clauses, depot.Offset(offset))
+	}
+	if limit > 0 {
+		clauses = append(clauses, depot.Limit(limit))
+	}
+	return r.find(ctx, clauses...)
+}
+
 func (r *MessageRepo) LoadByID(ctx context.Context, ID string) (*models.Message, error) {
 	tx := depot.MustGetTx(ctx)
 	vals, err := tx.QueryOne(messageRepoCols, messageRepoTable, depot.Where(depot.Eq("id", ID)))
 	if err != nil {
-		err = fmt.Errorf("failed to load models.Message by ID: %w", err)
+		err = r.wrapError(fmt.Errorf("failed to load models.Message by ID: %w", err))
 		if !errors.Is(err, depot.ErrNoResult) {
 			tx.Error(err)
 		}
@@ -158,6 +264,33 @@ func (r *MessageRepo) LoadByID(ctx context.Context, ID string) (*models.Message,
 	return r.fromValues(vals)
 }
 
+func (r *MessageRepo) FindPage(ctx context.Context, cursor string, size int) ([]*models.Message, string, error) {
+	if size <= 0 {
+		return nil, "", fmt.Errorf("failed to load models.Message page: invalid page size %d", size)
+	}
+
+	orderBy := depot.OrderBy(depot.Asc("id"))
+	clauses := []depot.SelectClause{orderBy, depot.Limit(size)}
+	if cursor != "" {
+		after, err := depot.SeekAfterToken(orderBy, cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to load models.Message page: %w", err)
+		}
+		clauses = append(clauses, depot.Where(after))
+	}
+
+	entities, err := r.find(ctx, clauses...)
+	if err != nil || len(entities) == 0 || len(entities) < size {
+		return entities, "", err
+	}
+
+	next, err := depot.KeysetToken(entities[len(entities)-1].ID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load models.Message page: %w", err)
+	}
+	return entities, next, nil
+}
+
 func (r *MessageRepo) toValues(entity *models.Message) depot.Values {
 	return depot.Values{
 		"id":          entity.ID,
@@ -174,33 +307,73 @@ func (r *MessageRepo) Insert(ctx context.Context, entity *models.Message) error
 	tx := depot.MustGetTx(ctx)
 	err := tx.InsertOne(messageRepoTable, r.toValues(entity))
 	if err != nil {
-		err = fmt.Errorf("failed to insert models.Message: %w", err)
+		err = r.wrapError(fmt.Errorf("failed to insert models.Message: %w", err))
 	}
 	return err
 }
 
-func (r *MessageRepo) delete(ctx context.Context, clauses ...depot.WhereClause) error {
+func (r *MessageRepo) InsertAll(ctx context.Context, entities []*models.Message) error {
 	tx := depot.MustGetTx(ctx)
-	err := tx.DeleteMany(messageRepoTable, clauses...)
+	rows := make([]depot.Values, len(entities))
+	for i, entity := range entities {
+		rows[i] = r.toValues(entity)
+	}
+
+	err := tx.InsertMany(messageRepoTable, rows)
 	if err != nil {
-		err = fmt.Errorf("failed to delete models.Message: %w", err)
+		err = r.wrapError(fmt.Errorf("failed to insert models.Messages: %w", err))
 	}
 	return err
 }
 
+func (r *MessageRepo) delete(ctx context.Context, clauses ...depot.WhereClause) (int64, error) {
+	tx := depot.MustGetTx(ctx)
+	n, err := tx.DeleteMany(messageRepoTable, clauses...)
+	if err != nil {
+		err = r.wrapError(fmt.Errorf("failed to delete models.Message: %w", err))
+	}
+	return n, err
+}
+
 func (r *MessageRepo) Update(ctx context.Context, entity *models.Message) error {
 	tx := depot.MustGetTx(ctx)
-	err := tx.UpdateMany(messageRepoTable, r.toValues(entity), depot.Where(depot.Eq("id", entity.ID)))
+	n, err := tx.UpdateMany(messageRepoTable, r.toValues(entity), depot.Where(depot.Eq("id", entity.ID)))
+	if err != nil {
+		return r.wrapError(fmt.Errorf("failed to update models.Message: %w", err))
+	}
+	if n == 0 {
+		// MySQL reports unchanged rows as not affected, so check whether the row exists.
+		count, err := r.count(ctx, depot.Where(depot.Eq("id", entity.ID)))
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return r.wrapError(fmt.Errorf("failed to update models.Message: %w", depot.ErrNoResult))
+		}
+	}
+	return nil
+}
+
+func (r *MessageRepo) Save(ctx context.Context, entity *models.Message) error {
+	tx := depot.MustGetTx(ctx)
+	err := tx.Upsert(messageRepoTable, r.toValues(entity), depot.Cols("id"), nil)
 	if err != nil {
-		err = fmt.Errorf("failed to update models.Message: %w", err)
+		err = r.wrapError(fmt.Errorf("failed to save models.Message: %w", err))
 	}
 	return err
 }
 
 func (r *MessageRepo) DeleteByID(ctx context.Context, ID string) error {
-	return r.delete(ctx, depot.Where(depot.Eq("id", ID)))
+	n, err := r.delete(ctx, depot.Where(depot.Eq("id", ID)))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return r.wrapError(fmt.Errorf("failed to delete models.Message: %w", depot.ErrNoResult))
+	}
+	return nil
 }
 
 func (r *MessageRepo) Delete(ctx context.Context, entity *models.Message) error {
-	return r.delete(ctx, depot.Where(depot.Eq("id", entity.ID)))
+	return r.DeleteByID(ctx, entity.ID)
 }
@@ -0,0 +1,130 @@
+// Copyright 2021 Alexander Metzner.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depot
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Expression defines a SQL expression used as a value in Values. Expressions are written verbatim into
+// insert and update statements instead of being bound as a parameter. This allows to compute values inside
+// the database, such as incrementing a counter.
+type Expression interface {
+	// Write writes the expression to the given writer.
+	Write(w ClauseWriter)
+
+	expr()
+}
+
+type rawExpression struct {
+	sql  string
+	args []interface{}
+}
+
+func (e *rawExpression) expr() {}
+
+func (e *rawExpression) Write(w ClauseWriter) {
+	s := e.sql
+	for _, arg := range e.args {
+		i := strings.IndexRune(s, '?')
+		w.WriteString(s[:i])
+		WriteValue(w, arg)
+		s = s[i+1:]
+	}
+	w.WriteString(s)
+}
+
+// Expr creates an Expression from sql. Every ? contained in sql is replaced with a parameter bound to the
+// corresponding arg. Args may be Expressions themselves which are written in place. Expr panics if the
+// number of placeholders does not match the number of args.
+func Expr(sql string, args ...interface{}) Expression {
+	if n := strings.Count(sql, "?"); n != len(args) {
+		panic(fmt.Sprintf("depot: expression '%s' contains %d placeholders but got %d args", sql, n, len(args)))
+	}
+
+	return &rawExpression{
+		sql:  sql,
+		args: args,
+	}
+}
+
+type incrementExpression struct {
+	column string
+	n      interface{}
+}
+
+func (e *incrementExpression) expr() {}
+
+func (e *incrementExpression) Write(w ClauseWriter) {
+	WriteIdentifier(w, e.column)
+	w.WriteString(" + ")
+	WriteValue(w, e.n)
+}
+
+// Increment creates an Expression adding n to the current value of column.
+func Increment(column string, n interface{}) Expression {
+	return &incrementExpression{
+		column: column,
+		n:      n,
+	}
+}
+
+type coalesceExpression struct {
+	column string
+	val    interface{}
+}
+
+func (e *coalesceExpression) expr() {}
+
+func (e *coalesceExpression) Write(w ClauseWriter) {
+	w.WriteString("coalesce(")
+	WriteIdentifier(w, e.column)
+	w.WriteString(", ")
+	WriteValue(w, e.val)
+	w.WriteRune(')')
+}
+
+// Coalesce creates an Expression evaluating to the current value of column or val if column is null.
+func Coalesce(column string, val interface{}) Expression {
+	return &coalesceExpression{
+		column: column,
+		val:    val,
+	}
+}
+
+type nowExpression struct{}
+
+func (nowExpression) expr() {}
+
+func (nowExpression) Write(w ClauseWriter) {
+	w.WriteString(w.Dialect().CurrentTimestamp())
+}
+
+// Now creates an Expression evaluating to the current timestamp of the database. The expression is rendered
+// by the Dialect.
+func Now() Expression {
+	return nowExpression{}
+}
+
+// WriteValue writes val to w. Expressions are written verbatim while all other values are bound as
+// parameters. It is exported to be used by Dialects rendering statements that contain values.
+func WriteValue(w ClauseWriter, val interface{}) {
+	if e, ok := val.(Expression); ok {
+		e.Write(w)
+		return
+	}
+	w.BindParameter(val)
+}
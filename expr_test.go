@@ -0,0 +1,74 @@
+// Copyright 2021 Alexander Metzner.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpressions(t *testing.T) {
+	tests := map[string]struct {
+		values       Values
+		expectedSQL  string
+		expectedArgs []interface{}
+	}{
+		"increment": {
+			values:       Values{"counter": Increment("counter", 1)},
+			expectedSQL:  `update "counters" set "counter" = "counter" + ? where ("id" = ?)`,
+			expectedArgs: []interface{}{1, "1"},
+		},
+		"now": {
+			values:       Values{"text": "hello", "updated": Now()},
+			expectedSQL:  `update "counters" set "text" = ?, "updated" = current_timestamp where ("id" = ?)`,
+			expectedArgs: []interface{}{"hello", "1"},
+		},
+		"coalesce": {
+			values:       Values{"counter": Coalesce("counter", 0)},
+			expectedSQL:  `update "counters" set "counter" = coalesce("counter", ?) where ("id" = ?)`,
+			expectedArgs: []interface{}{0, "1"},
+		},
+		"nested": {
+			values:       Values{"counter": Expr("greatest(?, ?)", Increment("counter", 2), 10)},
+			expectedSQL:  `update "counters" set "counter" = greatest("counter" + ?, ?) where ("id" = ?)`,
+			expectedArgs: []interface{}{2, 10, "1"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cb := NewDefaultClauseBuilder(&DefaultDialect{})
+			writeUpdate(cb, Table("counters"), test.values, []WhereClause{Where(Eq("id", "1"))})
+
+			if cb.SQL() != test.expectedSQL {
+				t.Errorf("expected\n%s\nbut got\n%s", test.expectedSQL, cb.SQL())
+			}
+
+			if !reflect.DeepEqual(cb.Args(), test.expectedArgs) {
+				t.Errorf("expected args %v but got %v", test.expectedArgs, cb.Args())
+			}
+		})
+	}
+}
+
+func TestExprPanicsOnArgMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+
+	Expr("? + ?", 1)
+}
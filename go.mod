@@ -17,6 +17,7 @@ module github.com/halimath/depot
 go 1.14
 
 require (
+	github.com/go-sql-driver/mysql v1.6.0
 	github.com/mattn/go-sqlite3 v1.14.6
 	golang.org/x/tools v0.1.0
 )
@@ -0,0 +1,115 @@
+// Copyright 2021 Alexander Metzner.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depot
+
+import (
+	"context"
+	"time"
+)
+
+// Hook defines an interceptor which is called before and after every statement executed by a Tx as well as
+// when beginning, committing or rolling back a transaction. Hooks are registered using Options.Hooks and can
+// be used to implement tracing, metrics or audit logging.
+type Hook interface {
+	// Before is called before the operation described by event is executed. The returned Context is used to
+	// execute the statement and is passed to After, which allows hooks to propagate values such as tracing
+	// spans.
+	Before(ctx context.Context, event *HookEvent) context.Context
+
+	// After is called once the operation has finished. event contains the duration, the number of rows and
+	// the error returned from the operation.
+	After(ctx context.Context, event *HookEvent)
+}
+
+// HookEvent describes a single operation reported to a Hook.
+type HookEvent struct {
+	// TxID identifies the transaction the operation is executed in. Nested transactions share the ID of the
+	// outermost transaction.
+	TxID string
+
+	// Op is the name of the operation, such as QueryOne, InsertOne, BeginTx or Commit.
+	Op string
+
+	// SQL contains the statement executed. It is empty for BeginTx, Commit and Rollback.
+	SQL string
+
+	// Args contains the arguments bound to the statement.
+	Args []interface{}
+
+	// Duration is the time the operation took. For queries returning a Cursor, this includes the time
+	// until the Cursor has been closed. It is only set when calling After.
+	Duration time.Duration
+
+	// Rows contains the number of rows returned or affected by the statement. It is -1 if the number is not
+	// known. It is only set when calling After.
+	Rows int64
+
+	// Err contains the error returned from the operation. It is only set when calling After.
+	Err error
+}
+
+// interception tracks a single operation reported to hooks. A nil *interception is valid and does nothing,
+// which is used when no hooks are registered.
+type interception struct {
+	hooks []Hook
+	ctx   context.Context
+	start time.Time
+	event HookEvent
+}
+
+// intercept reports the start of the operation described by event to hooks and returns an interception
+// used to report the end of the operation. It returns nil if hooks is empty.
+func intercept(ctx context.Context, hooks []Hook, event HookEvent) *interception {
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	event.Rows = -1
+	i := &interception{
+		hooks: hooks,
+		ctx:   ctx,
+		event: event,
+	}
+
+	for _, h := range hooks {
+		i.ctx = h.Before(i.ctx, &i.event)
+	}
+
+	i.start = time.Now()
+	return i
+}
+
+// context returns the Context to execute the operation with. It returns ctx if i is nil.
+func (i *interception) context(ctx context.Context) context.Context {
+	if i == nil {
+		return ctx
+	}
+	return i.ctx
+}
+
+// finish reports the end of the operation to the hooks in reverse order.
+func (i *interception) finish(rows int64, err error) {
+	if i == nil {
+		return
+	}
+
+	i.event.Duration = time.Since(i.start)
+	i.event.Rows = rows
+	i.event.Err = err
+
+	for j := len(i.hooks) - 1; j >= 0; j-- {
+		i.hooks[j].After(i.ctx, &i.event)
+	}
+}
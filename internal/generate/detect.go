@@ -164,6 +164,10 @@ func parseTag(tag string, f *FieldMapping) (ok bool) {
 			f.Opts.ID = true
 		case "nullable":
 			f.Opts.Nullable = true
+		case "auto":
+			f.Opts.Auto = true
+		case "redact":
+			f.Opts.Redact = true
 		default:
 			return false
 		}
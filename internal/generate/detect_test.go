@@ -26,8 +26,8 @@ func Test_detectMapping(t *testing.T) {
 		type (
 			// Message demonstrates a persistent struct showing several mapped fields.
 			Message struct {
-				ID         string     "depot:\"id,id\""
-				Text       string     "depot:\"text\""
+				ID         string     "depot:\"id,id,auto\""
+				Text       string     "depot:\"text,redact\""
 				OrderIndex int        "depot:\"order_index\""
 				Length     float32    "depot:\"len\""
 				Attachment []byte     "depot:\"attachment\""
@@ -51,7 +51,8 @@ func Test_detectMapping(t *testing.T) {
 					Name: "string",
 				},
 				Opts: FieldOptions{
-					ID: true,
+					ID:   true,
+					Auto: true,
 				},
 			},
 			{
@@ -60,6 +61,9 @@ func Test_detectMapping(t *testing.T) {
 				Type: &NamedType{
 					Name: "string",
 				},
+				Opts: FieldOptions{
+					Redact: true,
+				},
 			},
 			{
 				Field:  "OrderIndex",
@@ -111,6 +111,10 @@ type FieldOptions struct {
 	ID bool
 	// Flag indicating whether values mapped to this field can be null.
 	Nullable bool
+	// Flag indicating that the value of this field is generated by the database on insert.
+	Auto bool
+	// Flag indicating that values of this field must not be written to logs.
+	Redact bool
 }
 
 // StructMapping defines how a single struct is mapped.
@@ -131,3 +135,13 @@ func (s *StructMapping) ID() *FieldMapping {
 
 	return nil
 }
+
+// AutoID returns the field mapping defining the primary key if its value
+// is generated by the database. It returns nil otherwise.
+func (s *StructMapping) AutoID() *FieldMapping {
+	if id := s.ID(); id != nil && id.Opts.Auto {
+		return id
+	}
+
+	return nil
+}
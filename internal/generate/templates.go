@@ -44,6 +44,59 @@ var (
 	{{lcFirst .Opts.RepoName}}Table = depot.Table("{{.Opts.TableName}}")
 )
 
+// Errors returned from {{.Opts.RepoName}}. Use errors.Is to check for them. Each error also matches the
+// depot error it is based on.
+var (
+	// Err{{.Mapping.Name}}NotFound is returned when no {{.Mapping.Name}} matches the query.
+	Err{{.Mapping.Name}}NotFound = &{{lcFirst .Opts.RepoName}}Error{msg: "{{lcFirst .Mapping.Name}} not found", err: depot.ErrNoResult}
+
+	// Err{{.Mapping.Name}}Duplicate is returned when storing a {{.Mapping.Name}} violates a unique constraint.
+	Err{{.Mapping.Name}}Duplicate = &{{lcFirst .Opts.RepoName}}Error{msg: "duplicate {{lcFirst .Mapping.Name}}", err: depot.ErrUniqueViolation}
+
+	// Err{{.Mapping.Name}}ForeignKeyViolation is returned when storing a {{.Mapping.Name}} violates a foreign key constraint.
+	Err{{.Mapping.Name}}ForeignKeyViolation = &{{lcFirst .Opts.RepoName}}Error{msg: "{{lcFirst .Mapping.Name}} violates a foreign key constraint", err: depot.ErrForeignKeyViolation}
+
+	// Err{{.Mapping.Name}}NotNullViolation is returned when storing a {{.Mapping.Name}} with null in a not null column.
+	Err{{.Mapping.Name}}NotNullViolation = &{{lcFirst .Opts.RepoName}}Error{msg: "{{lcFirst .Mapping.Name}} violates a not null constraint", err: depot.ErrNotNullViolation}
+
+	// Err{{.Mapping.Name}}CheckViolation is returned when storing a {{.Mapping.Name}} violates a check constraint.
+	Err{{.Mapping.Name}}CheckViolation = &{{lcFirst .Opts.RepoName}}Error{msg: "{{lcFirst .Mapping.Name}} violates a check constraint", err: depot.ErrCheckViolation}
+
+	// Err{{.Mapping.Name}}Deadlock is returned when the database aborted a statement accessing {{.Mapping.Name}} to resolve a deadlock.
+	Err{{.Mapping.Name}}Deadlock = &{{lcFirst .Opts.RepoName}}Error{msg: "deadlock accessing {{lcFirst .Mapping.Name}}", err: depot.ErrDeadlock}
+)
+
+// {{lcFirst .Opts.RepoName}}Errors lists the errors returned from {{.Opts.RepoName}}.
+var {{lcFirst .Opts.RepoName}}Errors = []*{{lcFirst .Opts.RepoName}}Error{
+	Err{{.Mapping.Name}}NotFound,
+	Err{{.Mapping.Name}}Duplicate,
+	Err{{.Mapping.Name}}ForeignKeyViolation,
+	Err{{.Mapping.Name}}NotNullViolation,
+	Err{{.Mapping.Name}}CheckViolation,
+	Err{{.Mapping.Name}}Deadlock,
+}
+
+// {{lcFirst .Opts.RepoName}}Error is the type of the errors returned from {{.Opts.RepoName}}. The exported
+// errors unwrap to the depot error they are based on. Errors returned from the methods match the exported
+// error of their kind using errors.Is and unwrap to the error reported by depot.
+type {{lcFirst .Opts.RepoName}}Error struct {
+	kind *{{lcFirst .Opts.RepoName}}Error
+	msg  string
+	err  error
+}
+
+func (e *{{lcFirst .Opts.RepoName}}Error) Error() string {
+	return e.msg
+}
+
+func (e *{{lcFirst .Opts.RepoName}}Error) Is(target error) bool {
+	return e.kind != nil && target == error(e.kind)
+}
+
+func (e *{{lcFirst .Opts.RepoName}}Error) Unwrap() error {
+	return e.err
+}
+
 type {{.Opts.RepoName}} struct {
 	db *depot.DB
 }
@@ -55,7 +108,7 @@ func (r *{{.Opts.RepoName}}) Begin(ctx context.Context) (context.Context, error)
 
 func (r *{{.Opts.RepoName}}) Commit(ctx context.Context) error {
 	tx := depot.MustGetTx(ctx)
-	return tx.Commit()
+	return r.wrapError(tx.Commit())
 }
 
 func (r *{{.Opts.RepoName}}) Rollback(ctx context.Context) error {
@@ -63,6 +116,17 @@ func (r *{{.Opts.RepoName}}) Rollback(ctx context.Context) error {
 	return tx.Rollback()
 }
 
+// wrapError wraps err so that it matches the error returned from {{.Opts.RepoName}} that corresponds to the
+// depot error contained in err. Other errors are returned unchanged.
+func (r *{{.Opts.RepoName}}) wrapError(err error) error {
+	for _, kind := range {{lcFirst .Opts.RepoName}}Errors {
+		if errors.Is(err, kind.err) {
+			return &{{lcFirst .Opts.RepoName}}Error{kind: kind, msg: err.Error(), err: err}
+		}
+	}
+	return err
+}
+
 func (r *{{.Opts.RepoName}}) fromValues(vals depot.Values) (*{{.Opts.EntityName}}, error) {
 	var ok bool
 	{{range .Mapping.Fields}}
@@ -89,7 +153,7 @@ func (r *{{.Opts.RepoName}}) find(ctx context.Context, clauses ...depot.SelectCl
 	tx := depot.MustGetTx(ctx)
 	vals, err := tx.QueryMany({{lcFirst .Opts.RepoName}}Cols, {{lcFirst .Opts.RepoName}}Table, clauses...)
 	if err != nil {
-		err = fmt.Errorf("failed to load {{.Opts.EntityName}}: %w", err)
+		err = r.wrapError(fmt.Errorf("failed to load {{.Opts.EntityName}}: %w", err))
 		tx.Error(err)
 		return nil, err
 	}
@@ -105,11 +169,42 @@ func (r *{{.Opts.RepoName}}) find(ctx context.Context, clauses ...depot.SelectCl
 	return res, nil
 }
 
+// findEach streams the {{.Opts.EntityName}}s matching clauses to fn one at a time. The rows are read while fn
+// is called, so fn must not use the transaction bound to ctx, i.e. it must not call other methods of the
+// repository. Use find to load all entities first if fn needs to access the database.
+func (r *{{.Opts.RepoName}}) findEach(ctx context.Context, fn func(*{{.Opts.EntityName}}) error, clauses ...depot.SelectClause) error {
+	tx := depot.MustGetTx(ctx)
+	cursor, err := tx.QueryIter({{lcFirst .Opts.RepoName}}Cols, {{lcFirst .Opts.RepoName}}Table, clauses...)
+	if err != nil {
+		err = r.wrapError(fmt.Errorf("failed to load {{.Opts.EntityName}}: %w", err))
+		tx.Error(err)
+		return err
+	}
+	defer cursor.Close()
+
+	for cursor.Next() {
+		entity, err := r.fromValues(cursor.Values())
+		if err != nil {
+			return err
+		}
+		if err := fn(entity); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		err = r.wrapError(fmt.Errorf("failed to load {{.Opts.EntityName}}: %w", err))
+		tx.Error(err)
+		return err
+	}
+	return nil
+}
+
 func (r *{{.Opts.RepoName}}) count(ctx context.Context, clauses ...depot.WhereClause) (int, error) {
 	tx := depot.MustGetTx(ctx)
 	count, err := tx.QueryCount({{lcFirst .Opts.RepoName}}Table, clauses...)
 	if err != nil {
-		err = fmt.Errorf("failed to count {{.Opts.EntityName}}: %w", err)
+		err = r.wrapError(fmt.Errorf("failed to count {{.Opts.EntityName}}: %w", err))
 		tx.Error(err)
 		return 0, err
 	}
@@ -117,13 +212,24 @@ func (r *{{.Opts.RepoName}}) count(ctx context.Context, clauses ...depot.WhereCl
 	return count, err	
 }
 
+func (r *{{.Opts.RepoName}}) FindAll(ctx context.Context, offset, limit int) ([]*{{.Opts.EntityName}}, error) {
+	clauses := []depot.SelectClause{ {{- with .Mapping.ID}}depot.OrderBy(depot.Asc("{{.Column}}")){{end -}} }
+	if offset > 0 {
+		clauses = append(clauses, depot.Offset(offset))
+	}
+	if limit > 0 {
+		clauses = append(clauses, depot.Limit(limit))
+	}
+	return r.find(ctx, clauses...)
+}
+
 {{if $id := .Mapping.ID}}
 
 	func (r *{{.Opts.RepoName}}) LoadBy{{$id.Field}}(ctx context.Context, {{$id.Field}} {{$id.Type.Expr}}) (*{{.Opts.EntityName}}, error) {
 		tx := depot.MustGetTx(ctx)
 		vals, err := tx.QueryOne({{lcFirst .Opts.RepoName}}Cols, {{lcFirst .Opts.RepoName}}Table, depot.Where(depot.Eq("{{$id.Column}}", {{$id.Field}})))
 		if err != nil {
-			err = fmt.Errorf("failed to load {{.Opts.EntityName}} by {{$id.Field}}: %w", err)
+			err = r.wrapError(fmt.Errorf("failed to load {{.Opts.EntityName}} by {{$id.Field}}: %w", err))
 			if !errors.Is(err, depot.ErrNoResult) {
 				tx.Error(err)
 			}
@@ -132,52 +238,148 @@ func (r *{{.Opts.RepoName}}) count(ctx context.Context, clauses ...depot.WhereCl
 		return r.fromValues(vals)
 	}
 
+	func (r *{{.Opts.RepoName}}) FindPage(ctx context.Context, cursor string, size int) ([]*{{.Opts.EntityName}}, string, error) {
+		if size <= 0 {
+			return nil, "", fmt.Errorf("failed to load {{.Opts.EntityName}} page: invalid page size %d", size)
+		}
+
+		orderBy := depot.OrderBy(depot.Asc("{{$id.Column}}"))
+		clauses := []depot.SelectClause{orderBy, depot.Limit(size)}
+		if cursor != "" {
+			after, err := depot.SeekAfterToken(orderBy, cursor)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to load {{.Opts.EntityName}} page: %w", err)
+			}
+			clauses = append(clauses, depot.Where(after))
+		}
+
+		entities, err := r.find(ctx, clauses...)
+		if err != nil || len(entities) == 0 || len(entities) < size {
+			return entities, "", err
+		}
+
+		next, err := depot.KeysetToken(entities[len(entities)-1].{{$id.Field}})
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to load {{.Opts.EntityName}} page: %w", err)
+		}
+		return entities, next, nil
+	}
+
 {{end}}
 
 {{if not .Opts.ReadOnly}}
 
 	func (r *{{.Opts.RepoName}}) toValues(entity *{{.Opts.EntityName}}) depot.Values {
 		return depot.Values{
-			{{range .Mapping.Fields}}"{{.Column}}": entity.{{.Field}},
+			{{range .Mapping.Fields}}"{{.Column}}": {{if .Opts.Redact}}depot.Redacted(entity.{{.Field}}){{else}}entity.{{.Field}}{{end}},
 			{{end}}
 		}
 	}
 
-	func (r *{{.Opts.RepoName}}) Insert(ctx context.Context, entity *{{.Opts.EntityName}}) error {
+	{{if $auto := .Mapping.AutoID}}
+		func (r *{{.Opts.RepoName}}) Insert(ctx context.Context, entity *{{.Opts.EntityName}}) error {
+			tx := depot.MustGetTx(ctx)
+			vals := r.toValues(entity)
+			delete(vals, "{{$auto.Column}}")
+
+			generated, err := tx.InsertOneReturning({{lcFirst .Opts.RepoName}}Table, vals, depot.Cols("{{$auto.Column}}"))
+			if err != nil {
+				return r.wrapError(fmt.Errorf("failed to insert {{.Opts.EntityName}}: %w", err))
+			}
+
+			var ok bool
+			{{ $auto.Type.AssignNonNil (printf "entity.%s" $auto.Field) "ok" "generated" (printf "%q" $auto.Column) }}
+			if !ok {
+				return fmt.Errorf("failed to get generated {{$auto.Column}} for {{.Opts.EntityName}}: invalid value: %#v", generated["{{$auto.Column}}"])
+			}
+			return nil
+		}
+	{{else}}
+		func (r *{{.Opts.RepoName}}) Insert(ctx context.Context, entity *{{.Opts.EntityName}}) error {
+			tx := depot.MustGetTx(ctx)
+			err := tx.InsertOne({{lcFirst .Opts.RepoName}}Table, r.toValues(entity))
+			if err != nil {
+				err = r.wrapError(fmt.Errorf("failed to insert {{.Opts.EntityName}}: %w", err))
+			}
+			return err
+		}
+	{{end}}
+
+	func (r *{{.Opts.RepoName}}) InsertAll(ctx context.Context, entities []*{{.Opts.EntityName}}) error {
 		tx := depot.MustGetTx(ctx)
-		err := tx.InsertOne({{lcFirst .Opts.RepoName}}Table, r.toValues(entity))
+		rows := make([]depot.Values, len(entities))
+		for i, entity := range entities {
+			rows[i] = r.toValues(entity)
+			{{- with .Mapping.AutoID}}
+			delete(rows[i], "{{.Column}}")
+			{{- end}}
+		}
+
+		err := tx.InsertMany({{lcFirst .Opts.RepoName}}Table, rows)
 		if err != nil {
-			err = fmt.Errorf("failed to insert {{.Opts.EntityName}}: %w", err)
+			err = r.wrapError(fmt.Errorf("failed to insert {{.Opts.EntityName}}s: %w", err))
 		}
 		return err
 	}
 
-	func (r *{{.Opts.RepoName}}) delete(ctx context.Context, clauses... depot.WhereClause) error {
+	func (r *{{.Opts.RepoName}}) delete(ctx context.Context, clauses... depot.WhereClause) (int64, error) {
 		tx := depot.MustGetTx(ctx)
-		err := tx.DeleteMany({{lcFirst .Opts.RepoName}}Table, clauses...)
+		n, err := tx.DeleteMany({{lcFirst .Opts.RepoName}}Table, clauses...)
 		if err != nil {
-			err = fmt.Errorf("failed to delete {{.Opts.EntityName}}: %w", err)
+			err = r.wrapError(fmt.Errorf("failed to delete {{.Opts.EntityName}}: %w", err))
 		}
-		return err
+		return n, err
 	}
 
 	{{if $id := .Mapping.ID}}
 
 		func (r *{{.Opts.RepoName}}) Update(ctx context.Context, entity *{{.Opts.EntityName}}) error {
 			tx := depot.MustGetTx(ctx)
-			err := tx.UpdateMany({{lcFirst .Opts.RepoName}}Table, r.toValues(entity), depot.Where(depot.Eq("{{$id.Column}}", entity.{{$id.Field}})))
+			n, err := tx.UpdateMany({{lcFirst .Opts.RepoName}}Table, r.toValues(entity), depot.Where(depot.Eq("{{$id.Column}}", entity.{{$id.Field}})))
 			if err != nil {
-				err = fmt.Errorf("failed to update {{.Opts.EntityName}}: %w", err)
+				return r.wrapError(fmt.Errorf("failed to update {{.Opts.EntityName}}: %w", err))
+			}
+			if n == 0 {
+				// MySQL reports unchanged rows as not affected, so check whether the row exists.
+				count, err := r.count(ctx, depot.Where(depot.Eq("{{$id.Column}}", entity.{{$id.Field}})))
+				if err != nil {
+					return err
+				}
+				if count == 0 {
+					return r.wrapError(fmt.Errorf("failed to update {{.Opts.EntityName}}: %w", depot.ErrNoResult))
+				}
+			}
+			return nil
+		}
+
+		func (r *{{.Opts.RepoName}}) Save(ctx context.Context, entity *{{.Opts.EntityName}}) error {
+			{{- if .Mapping.AutoID}}
+			var zero {{$id.Type.Expr}}
+			if entity.{{$id.Field}} == zero {
+				return r.Insert(ctx, entity)
+			}
+			{{end}}
+			tx := depot.MustGetTx(ctx)
+			err := tx.Upsert({{lcFirst .Opts.RepoName}}Table, r.toValues(entity), depot.Cols("{{$id.Column}}"), nil)
+			if err != nil {
+				err = r.wrapError(fmt.Errorf("failed to save {{.Opts.EntityName}}: %w", err))
 			}
 			return err
 		}
 
 		func (r *{{.Opts.RepoName}}) DeleteBy{{$id.Field}}(ctx context.Context, {{$id.Field}} {{$id.Type.Expr}}) error {
-			return r.delete(ctx, depot.Where(depot.Eq("{{$id.Column}}", {{$id.Field}})))
+			n, err := r.delete(ctx, depot.Where(depot.Eq("{{$id.Column}}", {{$id.Field}})))
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				return r.wrapError(fmt.Errorf("failed to delete {{.Opts.EntityName}}: %w", depot.ErrNoResult))
+			}
+			return nil
 		}
 
 		func (r *{{.Opts.RepoName}}) Delete(ctx context.Context, entity *{{.Opts.EntityName}}) error {
-			return r.delete(ctx, depot.Where(depot.Eq("{{$id.Column}}", entity.{{$id.Field}})))
+			return r.DeleteBy{{$id.Field}}(ctx, entity.{{$id.Field}})
 		}
 
 	{{end}}
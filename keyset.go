@@ -0,0 +1,187 @@
+// Copyright 2021 Alexander Metzner.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depot
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"fmt"
+	"time"
+)
+
+func init() {
+	// Register all types returned from database drivers which are not registered with gob by default.
+	gob.Register(time.Time{})
+}
+
+// keysetCondition implements a SearchCondition selecting the rows following a given row when ordered by
+// a list of columns.
+type keysetCondition struct {
+	cols   []OrderByCol
+	values []interface{}
+}
+
+// SeekAfter creates a SearchCondition used for keyset (or seek) pagination. It matches all rows that come
+// after the row identified by values when ordered by orderBy. values contains the last seen values of the
+// columns listed in orderBy in the same order. SeekAfter panics if orderBy contains no columns or if the
+// number of values does not match the number of columns.
+//
+// The condition is rendered as a row value comparison, i.e. (a, b) > (?, ?) if all columns are sorted in
+// the same direction and the dialect supports row values. Otherwise the expanded form
+// (a > ?) or (a = ? and b > ?) is used.
+func SeekAfter(orderBy OrderByClause, values ...interface{}) SearchCondition {
+	cols := orderBy.Cols()
+	if len(cols) == 0 {
+		panic("SeekAfter: order by contains no columns")
+	}
+	if len(cols) != len(values) {
+		panic(fmt.Sprintf("SeekAfter: got %d values for %d order by columns", len(values), len(cols)))
+	}
+
+	return &keysetCondition{
+		cols:   cols,
+		values: values,
+	}
+}
+
+// SeekAfterToken works like SeekAfter but takes the last seen values from a token created with
+// KeysetToken. It returns an error if orderBy contains no columns or if the token does not contain a value
+// for each column.
+func SeekAfterToken(orderBy OrderByClause, token string) (SearchCondition, error) {
+	if len(orderBy.Cols()) == 0 {
+		return nil, fmt.Errorf("invalid keyset: order by contains no columns")
+	}
+
+	values, err := decodeKeysetToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if cols := orderBy.Cols(); len(values) != len(cols) {
+		return nil, fmt.Errorf("invalid keyset token: got %d values for %d order by columns", len(values), len(cols))
+	}
+
+	return SeekAfter(orderBy, values...), nil
+}
+
+// KeysetToken encodes the given values (usually the values of the order by columns of the last row of a
+// page) into an opaque token that can be handed out to clients and passed to SeekAfterToken to fetch the
+// next page.
+func KeysetToken(values ...interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(values); err != nil {
+		return "", fmt.Errorf("failed to encode keyset token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func decodeKeysetToken(token string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keyset token: %w", err)
+	}
+
+	var values []interface{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&values); err != nil {
+		return nil, fmt.Errorf("invalid keyset token: %w", err)
+	}
+
+	return values, nil
+}
+
+func (k *keysetCondition) Write(w ClauseWriter) {
+	if k.uniformDirection() && (len(k.cols) == 1 || w.Dialect().SupportsRowValues()) {
+		k.writeRowValues(w)
+		return
+	}
+
+	k.writeExpanded(w)
+}
+
+// uniformDirection returns whether all columns are sorted in the same direction.
+func (k *keysetCondition) uniformDirection() bool {
+	for _, c := range k.cols[1:] {
+		if c.Ascending != k.cols[0].Ascending {
+			return false
+		}
+	}
+	return true
+}
+
+// writeRowValues writes the condition using a row value comparison.
+func (k *keysetCondition) writeRowValues(w ClauseWriter) {
+	if len(k.cols) > 1 {
+		w.WriteRune('(')
+	}
+	for i, c := range k.cols {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		WriteIdentifier(w, c.Name)
+	}
+	if len(k.cols) > 1 {
+		w.WriteRune(')')
+	}
+
+	w.WriteRune(' ')
+	w.WriteString(seekOperator(k.cols[0]))
+	w.WriteRune(' ')
+
+	if len(k.cols) > 1 {
+		w.WriteRune('(')
+	}
+	for i, v := range k.values {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		w.BindParameter(v)
+	}
+	if len(k.cols) > 1 {
+		w.WriteRune(')')
+	}
+}
+
+// writeExpanded writes the condition as a disjunction of conjunctions.
+func (k *keysetCondition) writeExpanded(w ClauseWriter) {
+	for i, c := range k.cols {
+		if i > 0 {
+			w.WriteString(" or ")
+		}
+
+		w.WriteRune('(')
+		for j := 0; j < i; j++ {
+			WriteIdentifier(w, k.cols[j].Name)
+			w.WriteString(" = ")
+			w.BindParameter(k.values[j])
+			w.WriteString(" and ")
+		}
+		WriteIdentifier(w, c.Name)
+		w.WriteRune(' ')
+		w.WriteString(seekOperator(c))
+		w.WriteRune(' ')
+		w.BindParameter(k.values[i])
+		w.WriteRune(')')
+	}
+}
+
+// seekOperator returns the comparison operator used to select the rows following a value of col.
+func seekOperator(col OrderByCol) string {
+	if col.Ascending {
+		return ">"
+	}
+	return "<"
+}
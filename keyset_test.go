@@ -0,0 +1,113 @@
+// Copyright 2021 Alexander Metzner.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depot
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// rowValuesDialect is a DefaultDialect supporting row values.
+type rowValuesDialect struct {
+	DefaultDialect
+}
+
+func (d *rowValuesDialect) NewClauseBuilder() QueryBuilder { return NewDefaultClauseBuilder(d) }
+func (d *rowValuesDialect) SupportsRowValues() bool        { return true }
+
+func TestSeekAfter(t *testing.T) {
+	tests := []struct {
+		dialect  Dialect
+		orderBy  OrderByClause
+		values   []interface{}
+		expected string
+	}{
+		{
+			dialect:  &DefaultDialect{},
+			orderBy:  OrderBy(Asc("a")),
+			values:   []interface{}{1},
+			expected: `"a" > ?`,
+		},
+		{
+			dialect:  &DefaultDialect{},
+			orderBy:  OrderBy(Asc("a"), Asc("b")),
+			values:   []interface{}{1, 2},
+			expected: `("a" > ?) or ("a" = ? and "b" > ?)`,
+		},
+		{
+			dialect:  &rowValuesDialect{},
+			orderBy:  OrderBy(Desc("a"), Desc("b")),
+			values:   []interface{}{1, 2},
+			expected: `("a", "b") < (?, ?)`,
+		},
+		{
+			dialect:  &rowValuesDialect{},
+			orderBy:  OrderBy(Asc("a"), Desc("b")),
+			values:   []interface{}{1, 2},
+			expected: `("a" > ?) or ("a" = ? and "b" < ?)`,
+		},
+	}
+
+	for _, test := range tests {
+		cb := test.dialect.NewClauseBuilder()
+		SeekAfter(test.orderBy, test.values...).Write(cb)
+		if cb.SQL() != test.expected {
+			t.Errorf("expected '%s' but got '%s'", test.expected, cb.SQL())
+		}
+	}
+}
+
+func TestSeekAfterWithoutColumns(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected SeekAfter to panic")
+		}
+	}()
+
+	SeekAfter(OrderBy())
+}
+
+func TestKeysetToken(t *testing.T) {
+	now := time.Now().UTC()
+	values := []interface{}{"a", int64(17), now, nil}
+
+	token, err := KeysetToken(values...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := decodeKeysetToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(values, decoded) {
+		t.Errorf("expected %#v but got %#v", values, decoded)
+	}
+
+	if _, err := SeekAfterToken(OrderBy(Asc("a")), token); err == nil {
+		t.Errorf("expected error for mismatching number of values")
+	}
+
+	empty, err := KeysetToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := SeekAfterToken(OrderBy(), empty); err == nil {
+		t.Errorf("expected error for order by without columns")
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2021 Alexander Metzner.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depot
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogLevel defines the severity of a log message.
+type LogLevel int
+
+const (
+	// LevelDebug is used to log all statements executed.
+	LevelDebug LogLevel = iota
+
+	// LevelInfo is used for informational messages.
+	LevelInfo
+
+	// LevelWarn is used for conditions that should be looked at.
+	LevelWarn
+
+	// LevelError is used to log failed statements.
+	LevelError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("LEVEL(%d)", int(l))
+	}
+}
+
+// Logger defines the interface for structured loggers used by a DB. keyvals contains alternating keys and
+// values. Implementations are expected to filter messages based on their level.
+type Logger interface {
+	Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{})
+}
+
+// --
+
+type stdLogger struct {
+	logger *log.Logger
+	level  LogLevel
+}
+
+// NewStdLogger creates a Logger writing messages with at least the given level to logger. The key/value
+// pairs are formatted as key=value. If logger is nil, the standard logger of the log package is used.
+func NewStdLogger(logger *log.Logger, level LogLevel) Logger {
+	return &stdLogger{
+		logger: logger,
+		level:  level,
+	}
+}
+
+func (l *stdLogger) Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+	if level < l.level {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(level.String())
+	b.WriteRune(' ')
+	b.WriteString(msg)
+
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if s, ok := keyvals[i+1].(string); ok {
+			fmt.Fprintf(&b, " %v=%q", keyvals[i], s)
+		} else {
+			fmt.Fprintf(&b, " %v=%v", keyvals[i], keyvals[i+1])
+		}
+	}
+
+	if l.logger == nil {
+		log.Print(b.String())
+	} else {
+		l.logger.Print(b.String())
+	}
+}
+
+// --
+
+type jsonLogger struct {
+	mu    sync.Mutex
+	w     io.Writer
+	level LogLevel
+}
+
+// NewJSONLogger creates a Logger writing messages with at least the given level to w. Each message is
+// written as a single line containing a JSON object with the keys time, level and msg followed by the
+// key/value pairs. Values that cannot be marshaled to JSON are written as strings.
+func NewJSONLogger(w io.Writer, level LogLevel) Logger {
+	return &jsonLogger{
+		w:     w,
+		level: level,
+	}
+}
+
+func (l *jsonLogger) Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+	if level < l.level {
+		return
+	}
+
+	var b bytes.Buffer
+	b.WriteRune('{')
+	writeJSONField(&b, "time", time.Now().UTC().Format(time.RFC3339Nano))
+	b.WriteRune(',')
+	writeJSONField(&b, "level", level.String())
+	b.WriteRune(',')
+	writeJSONField(&b, "msg", msg)
+
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		b.WriteRune(',')
+		writeJSONField(&b, fmt.Sprint(keyvals[i]), keyvals[i+1])
+	}
+	b.WriteString("}\n")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(b.Bytes())
+}
+
+// writeJSONField writes key and val as a JSON object member to b.
+func writeJSONField(b *bytes.Buffer, key string, val interface{}) {
+	k, _ := json.Marshal(key)
+	b.Write(k)
+	b.WriteRune(':')
+
+	switch v := val.(type) {
+	case error:
+		val = v.Error()
+	case fmt.Stringer:
+		val = v.String()
+	}
+
+	data, err := json.Marshal(val)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(val))
+	}
+	b.Write(data)
+}
+
+// --
+
+// RedactedValue wraps a value bound to a statement to hide it from logs. Tx passes the wrapped value to the
+// driver unchanged, so the redaction only applies to logs and hooks.
+type RedactedValue struct {
+	val interface{}
+}
+
+// Redacted wraps val in a RedactedValue. Use it for values such as passwords or personal data that must
+// not be written to logs. val may be of any type supported by the driver.
+func Redacted(val interface{}) RedactedValue {
+	return RedactedValue{val: val}
+}
+
+// Value returns the wrapped value converted to a driver.Value. It is only used if a RedactedValue is passed
+// to the sql package directly; Tx unwraps the value before passing it to the driver.
+func (r RedactedValue) Value() (driver.Value, error) {
+	return driver.DefaultParameterConverter.ConvertValue(r.val)
+}
+
+// String returns a placeholder hiding the wrapped value.
+func (r RedactedValue) String() string {
+	return "[REDACTED]"
+}
+
+// --
+
+// logHook implements a Hook logging all operations to a Logger.
+type logHook struct {
+	logger Logger
+}
+
+func (h *logHook) Before(ctx context.Context, event *HookEvent) context.Context {
+	return ctx
+}
+
+func (h *logHook) After(ctx context.Context, event *HookEvent) {
+	keyvals := []interface{}{"tx", event.TxID}
+	if event.SQL != "" {
+		keyvals = append(keyvals, "sql", event.SQL)
+	}
+	if len(event.Args) > 0 {
+		keyvals = append(keyvals, "args", redactArgs(event.Args))
+	}
+	keyvals = append(keyvals, "elapsed", event.Duration)
+	if event.Rows >= 0 {
+		keyvals = append(keyvals, "rows", event.Rows)
+	}
+
+	if event.Err != nil {
+		h.logger.Log(ctx, LevelError, event.Op, append(keyvals, "error", event.Err)...)
+		return
+	}
+
+	h.logger.Log(ctx, LevelDebug, event.Op, keyvals...)
+}
+
+// redactArgs returns a copy of args with all RedactedValues replaced by a placeholder.
+func redactArgs(args []interface{}) []interface{} {
+	redacted := make([]interface{}, len(args))
+	for i, arg := range args {
+		if r, ok := arg.(RedactedValue); ok {
+			redacted[i] = r.String()
+		} else {
+			redacted[i] = arg
+		}
+	}
+	return redacted
+}
+
+// unredactArgs returns args with all RedactedValues replaced by the wrapped values. args is returned
+// unchanged if it contains no RedactedValue.
+func unredactArgs(args []interface{}) []interface{} {
+	var unredacted []interface{}
+	for i, arg := range args {
+		if r, ok := arg.(RedactedValue); ok {
+			if unredacted == nil {
+				unredacted = make([]interface{}, len(args))
+				copy(unredacted, args)
+			}
+			unredacted[i] = r.val
+		}
+	}
+
+	if unredacted == nil {
+		return args
+	}
+	return unredacted
+}
+
+// newTxID generates a random ID used to correlate the log messages of a single transaction.
+func newTxID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%016x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b[:])
+}
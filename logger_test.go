@@ -0,0 +1,126 @@
+// Copyright 2021 Alexander Metzner.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depot
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewStdLogger(log.New(&buf, "", 0), LevelInfo)
+
+	logger.Log(context.Background(), LevelDebug, "ignored", "key", "value")
+	logger.Log(context.Background(), LevelError, "QueryOne", "sql", "select 1", "rows", 0, "error", errors.New("failed"))
+
+	expected := "ERROR QueryOne sql=\"select 1\" rows=0 error=failed\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q but got %q", expected, buf.String())
+	}
+}
+
+func TestJSONLogger_levels(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewJSONLogger(&buf, LevelWarn)
+
+	logger.Log(context.Background(), LevelInfo, "ignored")
+	if buf.Len() != 0 {
+		t.Errorf("expected message below level to be ignored but got %q", buf.String())
+	}
+
+	logger.Log(context.Background(), LevelWarn, "logged", "ch", make(chan int))
+	if !bytes.Contains(buf.Bytes(), []byte(`"level":"WARN","msg":"logged","ch":"0x`)) {
+		t.Errorf("got unexpected output %q", buf.String())
+	}
+}
+
+// point is a type only supported by recordingConn.
+type point struct{ x, y int }
+
+// recordingDriver is a database/sql driver recording the arguments of executed statements. Its connections
+// accept point values using a NamedValueChecker like drivers supporting custom types do.
+type recordingDriver struct {
+	args []interface{}
+}
+
+func (d *recordingDriver) Open(name string) (driver.Conn, error) {
+	return &recordingConn{driver: d}, nil
+}
+
+type recordingConn struct {
+	driver *recordingDriver
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+func (c *recordingConn) Close() error              { return nil }
+func (c *recordingConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *recordingConn) Commit() error             { return nil }
+func (c *recordingConn) Rollback() error           { return nil }
+
+func (c *recordingConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if _, ok := nv.Value.(point); ok {
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+func (c *recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	for _, arg := range args {
+		c.driver.args = append(c.driver.args, arg.Value)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func TestRedactedDriverSpecificValue(t *testing.T) {
+	d := &recordingDriver{}
+	sql.Register("depot-recording", d)
+
+	var buf bytes.Buffer
+	db, err := Open("depot-recording", "", Options{
+		Dialect: &DefaultDialect{},
+		Logger:  NewStdLogger(log.New(&buf, "", 0), LevelDebug),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tx, _, err := db.BeginTx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	if err := tx.InsertOne(Into("points"), Values{"location": Redacted(point{1, 2})}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.args) != 1 || d.args[0] != (point{1, 2}) {
+		t.Errorf("expected wrapped value to be passed to the driver but got %v", d.args)
+	}
+
+	if strings.Contains(buf.String(), "{1 2}") || !strings.Contains(buf.String(), "[REDACTED]") {
+		t.Errorf("expected value to be redacted in logs but got %q", buf.String())
+	}
+}
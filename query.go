@@ -0,0 +1,125 @@
+// Copyright 2021 Alexander Metzner.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depot
+
+// Query defines a select query. A Query is used by Tx to execute select queries but it may also be used
+// as a subquery in search conditions or as a scalar subquery in a ColsClause. When written to a
+// ClauseWriter all parameters are bound to the writer in order, so a Query can be nested in another one.
+type Query struct {
+	cols    ColsClause
+	from    TableClause
+	clauses []SelectClause
+}
+
+// Select creates a new Query selecting cols from the given table applying all clauses.
+func Select(cols ColsClause, from TableClause, clauses ...SelectClause) *Query {
+	return &Query{
+		cols:    cols,
+		from:    from,
+		clauses: clauses,
+	}
+}
+
+// Write writes the query to w.
+func (q *Query) Write(w ClauseWriter) {
+	w.WriteString("select ")
+	q.cols.Write(w)
+	w.WriteString(" from ")
+	q.from.Write(w)
+	limit, offset := pickLimitOffset(q.clauses)
+	pickAndAppendWhere(w, q.clauses, limit == 0)
+	pickAndAppendGroupBy(w, q.clauses)
+	ordered := pickAndAppendOrderBy(w, q.clauses)
+	appendLimitOffset(w, limit, offset, ordered)
+}
+
+// As returns a Column selecting the result of q as a scalar subquery using alias as the column's name. q
+// must return at most a single row with a single column.
+func (q *Query) As(alias string) Column {
+	return &subqueryColumn{
+		query: q,
+		alias: alias,
+	}
+}
+
+type subqueryColumn struct {
+	query *Query
+	alias string
+}
+
+func (c *subqueryColumn) Name() string {
+	return c.alias
+}
+
+func (c *subqueryColumn) Write(w ClauseWriter) {
+	w.WriteRune('(')
+	c.query.Write(w)
+	w.WriteRune(')')
+	writeAlias(w, c.alias)
+}
+
+func (c *subqueryColumn) As(alias string) Column {
+	return c.query.As(alias)
+}
+
+type existsClause struct {
+	query *Query
+	not   bool
+}
+
+func (c *existsClause) Write(w ClauseWriter) {
+	if c.not {
+		w.WriteString("not ")
+	}
+	w.WriteString("exists (")
+	c.query.Write(w)
+	w.WriteRune(')')
+}
+
+// Exists creates a SearchCondition that is met when query returns at least one row.
+func Exists(query *Query) SearchCondition {
+	return &existsClause{
+		query: query,
+	}
+}
+
+// NotExists creates a SearchCondition that is met when query returns no rows.
+func NotExists(query *Query) SearchCondition {
+	return &existsClause{
+		query: query,
+		not:   true,
+	}
+}
+
+type inSubqueryClause struct {
+	column string
+	query  *Query
+}
+
+func (c *inSubqueryClause) Write(w ClauseWriter) {
+	WriteIdentifier(w, c.column)
+	w.WriteString(" in (")
+	c.query.Write(w)
+	w.WriteRune(')')
+}
+
+// InSubquery creates a SearchCondition matching all rows where column is contained in the results of
+// query. query must select a single column.
+func InSubquery(column string, query *Query) SearchCondition {
+	return &inSubqueryClause{
+		column: column,
+		query:  query,
+	}
+}
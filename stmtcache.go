@@ -0,0 +1,147 @@
+// Copyright 2021 Alexander Metzner.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depot
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+)
+
+// StmtCacheStats contains statistics about the prepared statement cache of a DB.
+type StmtCacheStats struct {
+	// Hits counts the lookups that found a prepared statement in the cache.
+	Hits int64
+
+	// Misses counts the lookups that required preparing a statement.
+	Misses int64
+
+	// Size is the number of statements currently cached.
+	Size int
+}
+
+// stmtCache implements a least recently used cache of prepared statements keyed by their SQL.
+type stmtCache struct {
+	// hits and misses are accessed atomically and come first to guarantee 64 bit alignment.
+	hits   int64
+	misses int64
+
+	pool     *sql.DB
+	capacity int
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+// stmtCacheEntry is the value stored in the elements of a stmtCache's list.
+type stmtCacheEntry struct {
+	query string
+	stmt  *sql.Stmt
+}
+
+func newStmtCache(pool *sql.DB, capacity int) *stmtCache {
+	return &stmtCache{
+		pool:     pool,
+		capacity: capacity,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element, capacity),
+	}
+}
+
+// lookup returns the cached prepared statement for query or nil if query has not been prepared, yet.
+// lookup never prepares a statement and thus never waits for a connection from the pool.
+func (c *stmtCache) lookup(query string) *sql.Stmt {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[query]; ok {
+		c.lru.MoveToFront(e)
+		atomic.AddInt64(&c.hits, 1)
+		return e.Value.(*stmtCacheEntry).stmt
+	}
+
+	atomic.AddInt64(&c.misses, 1)
+	return nil
+}
+
+// prepare prepares query and adds it to the cache unless it is already cached. Preparing the statement
+// requires a free connection from the pool, so prepare must not be called while holding a connection. If
+// the cache exceeds its capacity, the least recently used statement is evicted and closed. Closing is safe
+// even if the statement is still used by a transaction as the sql package defers closing until all
+// transaction bound statements have been closed.
+func (c *stmtCache) prepare(ctx context.Context, query string) error {
+	c.mu.Lock()
+	_, ok := c.entries[query]
+	c.mu.Unlock()
+	if ok {
+		return nil
+	}
+
+	// Prepare the statement without holding the lock as it requires a round trip to the database.
+	stmt, err := c.pool.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[query]; ok {
+		// Another goroutine prepared the same statement concurrently.
+		stmt.Close()
+		return nil
+	}
+
+	c.entries[query] = c.lru.PushFront(&stmtCacheEntry{query: query, stmt: stmt})
+
+	for c.lru.Len() > c.capacity {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		entry := e.Value.(*stmtCacheEntry)
+		delete(c.entries, entry.query)
+		entry.stmt.Close()
+	}
+
+	return nil
+}
+
+// stats returns the cache's current statistics.
+func (c *stmtCache) stats() StmtCacheStats {
+	c.mu.Lock()
+	size := c.lru.Len()
+	c.mu.Unlock()
+
+	return StmtCacheStats{
+		Hits:   atomic.LoadInt64(&c.hits),
+		Misses: atomic.LoadInt64(&c.misses),
+		Size:   size,
+	}
+}
+
+// close closes all cached statements and empties the cache.
+func (c *stmtCache) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for e := c.lru.Front(); e != nil; e = e.Next() {
+		e.Value.(*stmtCacheEntry).stmt.Close()
+	}
+
+	c.lru.Init()
+	c.entries = make(map[string]*list.Element, c.capacity)
+}
@@ -19,7 +19,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
+	"sort"
 )
 
 var (
@@ -34,55 +34,312 @@ var (
 
 // Tx defines a transaction with the database and is always bound to a single Context. It provides an abstract
 // interface built around Values and Clauses to read and write data from or to the database.
+//
+// Calling BeginTx with a Context that already carries a transaction creates a nested Tx. Unless
+// Options.FlattenNestedTx is set, a nested Tx is backed by a savepoint so it can be committed or rolled
+// back independently from the outer transaction.
 type Tx struct {
-	options           *Options
-	txCount           int
-	tx                *sql.Tx
-	ctx               context.Context
-	err               error
-	alreadyRolledback bool
+	options   *Options
+	tx        *sql.Tx
+	txOptions sql.TxOptions
+	ctx       context.Context
+	err       error
+	done      bool
+
+	// root points to the outermost transaction. It points to the Tx itself for the outermost transaction.
+	root *Tx
+
+	// savepoint contains the name of the savepoint backing a nested transaction. It is empty for the
+	// outermost transaction as well as for flattened nested transactions.
+	savepoint string
+
+	// savepoints counts the savepoints created so far. It is only used on the outermost transaction.
+	savepoints int
+
+	// rolledBack is set on the outermost transaction when a flattened nested transaction has rolled back
+	// the database transaction.
+	rolledBack bool
+
+	// id identifies the transaction in logs and hook events. It is only set on the outermost transaction.
+	id string
+
+	// stmts is the DB's prepared statement cache or nil if the cache is disabled.
+	stmts *stmtCache
+
+	// txStmts maps cached statements to the statements bound to this transaction. It is only used on the
+	// outermost transaction.
+	txStmts map[*sql.Stmt]*sql.Stmt
+
+	// unprepared collects the queries that missed the statement cache. They are prepared once the
+	// outermost transaction has released its connection. It is only used on the outermost transaction.
+	unprepared []string
 }
 
 // Commit commits the session's transaction and returns an error if the commit failtx.
+// Committing a nested transaction releases its savepoint. Committing a flattened nested transaction does
+// nothing besides marking it as done.
 func (tx *Tx) Commit() error {
 	if tx.err != nil {
 		return tx.err
 	}
 
-	// TODO: What if txCount is 0?
+	if tx.done {
+		return sql.ErrTxDone
+	}
+
+	if tx.root == tx {
+		tx.done = true
+		i := tx.intercept("Commit", "", nil)
+		err := tx.translate(tx.tx.Commit())
+		i.finish(-1, err)
+		tx.prepareUnprepared()
+		return err
+	}
 
-	if tx.txCount > 1 {
-		tx.txCount--
+	if tx.savepoint == "" {
+		if tx.root.err != nil {
+			return tx.root.err
+		}
+		tx.done = true
 		return nil
 	}
 
-	return tx.tx.Commit()
+	tx.done = true
+	return tx.execSavepoint("ReleaseSavepoint", tx.options.Dialect.ReleaseSavepoint(tx.savepoint))
 }
 
 // Rollback rolls the session's transaction back and returns any error raised during the rollback.
 // If the transaction has been committed before, it is safe to call Rollback without any error.
 // Thus, Rollback can safely be called using defer.
-func (tx *Tx) Rollback() (err error) {
-	if tx.txCount == 0 {
+//
+// Rolling back a nested transaction rolls back to its savepoint leaving the outer transaction intact.
+// Rolling back a flattened nested transaction rolls back the whole transaction and causes the outer
+// transaction's Commit to return ErrRollback.
+func (tx *Tx) Rollback() error {
+	if tx.done {
 		return nil
 	}
+	tx.done = true
+
+	if tx.savepoint != "" {
+		if err := tx.execSavepoint("RollbackToSavepoint", tx.options.Dialect.RollbackToSavepoint(tx.savepoint)); err != nil {
+			return err
+		}
+		return tx.execSavepoint("ReleaseSavepoint", tx.options.Dialect.ReleaseSavepoint(tx.savepoint))
+	}
 
-	if !tx.alreadyRolledback {
-		err = tx.tx.Rollback()
+	if tx.root != tx {
+		tx.root.err = ErrRollback
 	}
 
-	if tx.txCount > 1 {
-		tx.txCount--
-		tx.err = ErrRollback
+	if tx.root.rolledBack {
+		return nil
 	}
+	tx.root.rolledBack = true
 
-	return
+	i := tx.intercept("Rollback", "", nil)
+	err := tx.tx.Rollback()
+	i.finish(-1, err)
+	tx.root.prepareUnprepared()
+	return err
 }
 
 // Error marks the transaction as failed so it cannot be committed later on. Calling Error with a nil error
-// clears the error state of the transaction.
+// clears the error state of the transaction. Calling Error on a flattened nested transaction marks the
+// outermost transaction as failed, too.
 func (tx *Tx) Error(err error) {
 	tx.err = err
+	if tx.root != tx && tx.savepoint == "" {
+		tx.root.err = err
+	}
+}
+
+// begin creates a nested transaction joining tx. ctx is bound to the nested transaction.
+func (tx *Tx) begin(ctx context.Context) (*Tx, error) {
+	nested := &Tx{
+		options:   tx.options,
+		tx:        tx.tx,
+		txOptions: tx.txOptions,
+		ctx:       ctx,
+		root:      tx.root,
+		stmts:     tx.stmts,
+	}
+
+	if tx.options.FlattenNestedTx {
+		return nested, nil
+	}
+
+	tx.root.savepoints++
+	nested.savepoint = fmt.Sprintf("depot_sp_%d", tx.root.savepoints)
+
+	if err := nested.execSavepoint("Savepoint", tx.options.Dialect.Savepoint(nested.savepoint)); err != nil {
+		return nil, err
+	}
+
+	return nested, nil
+}
+
+// execSavepoint executes the savepoint statement query reported to hooks as op. An empty query is not
+// executed.
+func (tx *Tx) execSavepoint(op, query string) error {
+	if query == "" {
+		return nil
+	}
+
+	i := tx.intercept(op, query, nil)
+	_, err := tx.tx.ExecContext(i.context(tx.ctx), query)
+	i.finish(-1, err)
+
+	if err != nil {
+		return fmt.Errorf("failed to execute '%s': %w", query, err)
+	}
+
+	return nil
+}
+
+// translate lets the Dialect translate err. It returns nil if err is nil.
+func (tx *Tx) translate(err error) error {
+	if err == nil {
+		return nil
+	}
+	return tx.options.Dialect.TranslateError(err)
+}
+
+// intercept reports the start of the operation op executing query to the hooks.
+func (tx *Tx) intercept(op, query string, args []interface{}) *interception {
+	return intercept(tx.ctx, tx.options.Hooks, HookEvent{
+		TxID: tx.root.id,
+		Op:   op,
+		SQL:  query,
+		Args: args,
+	})
+}
+
+// prepared returns the cached prepared statement for query bound to the transaction. It returns nil if the
+// statement cache is disabled or query has not been cached, yet, in which case the query should be executed
+// directly. A query missing the cache is not prepared right away as that would wait for a second connection
+// from the pool while the transaction holds one; it is prepared after the transaction has finished instead.
+func (tx *Tx) prepared(ctx context.Context, query string) *sql.Stmt {
+	if tx.stmts == nil {
+		return nil
+	}
+
+	stmt := tx.stmts.lookup(query)
+	if stmt == nil {
+		tx.root.unprepared = append(tx.root.unprepared, query)
+		return nil
+	}
+
+	if txStmt, ok := tx.root.txStmts[stmt]; ok {
+		return txStmt
+	}
+
+	if tx.root.txStmts == nil {
+		tx.root.txStmts = make(map[*sql.Stmt]*sql.Stmt)
+	}
+
+	txStmt := tx.tx.StmtContext(ctx, stmt)
+	tx.root.txStmts[stmt] = txStmt
+	return txStmt
+}
+
+// prepareUnprepared adds the queries that missed the statement cache during the transaction to the cache.
+// It must only be called after the transaction has released its connection. Errors are ignored as the
+// statement is prepared again on the next miss.
+func (tx *Tx) prepareUnprepared() {
+	for _, query := range tx.unprepared {
+		if err := tx.stmts.prepare(tx.ctx, query); err != nil {
+			break
+		}
+	}
+	tx.unprepared = nil
+}
+
+// queryContext executes a query returning rows using a cached prepared statement if possible. The
+// returned interception must be finished once the rows have been consumed.
+func (tx *Tx) queryContext(op, query string, args []interface{}) (*sql.Rows, *interception, error) {
+	i := tx.intercept(op, query, args)
+	ctx := i.context(tx.ctx)
+	args = unredactArgs(args)
+
+	var rows *sql.Rows
+	var err error
+	if stmt := tx.prepared(ctx, query); stmt != nil {
+		rows, err = stmt.QueryContext(ctx, args...)
+	} else {
+		rows, err = tx.tx.QueryContext(ctx, query, args...)
+	}
+
+	if err != nil {
+		err = tx.translate(err)
+		i.finish(0, err)
+		return nil, nil, err
+	}
+
+	return rows, i, nil
+}
+
+// queryRow executes a query returning at most one row using a cached prepared statement if possible and
+// passes the row to scan. It returns the error returned from scan.
+func (tx *Tx) queryRow(op, query string, args []interface{}, scan func(row Scanner) error) error {
+	i := tx.intercept(op, query, args)
+	ctx := i.context(tx.ctx)
+	args = unredactArgs(args)
+
+	var row *sql.Row
+	if stmt := tx.prepared(ctx, query); stmt != nil {
+		row = stmt.QueryRowContext(ctx, args...)
+	} else {
+		row = tx.tx.QueryRowContext(ctx, query, args...)
+	}
+
+	err := scan(row)
+	switch {
+	case err == nil:
+		i.finish(1, nil)
+	case errors.Is(err, sql.ErrNoRows):
+		i.finish(0, nil)
+	default:
+		err = tx.translate(err)
+		i.finish(0, err)
+	}
+
+	return err
+}
+
+// exec executes a statement using a cached prepared statement if possible.
+func (tx *Tx) exec(op, query string, args []interface{}) (sql.Result, error) {
+	i := tx.intercept(op, query, args)
+	ctx := i.context(tx.ctx)
+	args = unredactArgs(args)
+
+	var res sql.Result
+	var err error
+	if stmt := tx.prepared(ctx, query); stmt != nil {
+		res, err = stmt.ExecContext(ctx, args...)
+	} else {
+		res, err = tx.tx.ExecContext(ctx, query, args...)
+	}
+	err = tx.translate(err)
+
+	rows := int64(-1)
+	if err == nil {
+		if n, rerr := res.RowsAffected(); rerr == nil {
+			rows = n
+		}
+	}
+	i.finish(rows, err)
+
+	return res, err
+}
+
+// satisfies returns whether a nested transaction requesting opts can join tx.
+func (tx *Tx) satisfies(opts sql.TxOptions) bool {
+	if opts.Isolation != sql.LevelDefault && opts.Isolation != tx.txOptions.Isolation {
+		return false
+	}
+
+	return opts.ReadOnly || !tx.txOptions.ReadOnly
 }
 
 // QueryOne executes a query that is expected to return a single result. // The query selects cols using from
@@ -97,59 +354,166 @@ func (tx *Tx) QueryOne(cols ColsClause, from TableClause, where ...WhereClause)
 	from.Write(cb)
 	appendWhere(cb, where)
 
-	query := cb.SQL()
-
-	if tx.options.LogSQL {
-		log.Printf("QueryOne: '%s'", query)
+	values, err := tx.first("QueryOne", cb.SQL(), cb.Args(), cols.Names())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoResult
 	}
+	return values, err
+}
 
-	row := tx.tx.QueryRowContext(tx.ctx, query, cb.Args()...)
-	values, err := collectValues(cols.Names(), row)
+// first executes query and returns the values of the first row using names as the keys. It returns
+// sql.ErrNoRows if the query does not return any row.
+func (tx *Tx) first(op, query string, args []interface{}, names []string) (Values, error) {
+	cursor, err := tx.cursor(op, query, args, names)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrNoResult
+		return nil, err
+	}
+	defer cursor.Close()
+
+	if !cursor.Next() {
+		if err := cursor.Err(); err != nil {
+			return nil, err
 		}
-		return nil, fmt.Errorf("failed to execute '%s': %w", query, err)
+		return nil, sql.ErrNoRows
 	}
 
-	return values, nil
+	return cursor.Values(), nil
 }
 
 // QueryMany executes a query that is expected to match any number of rowtx. The rows are returned as Valuetx.
 // cols are selected using from and all other clauses are applied.
 func (tx *Tx) QueryMany(cols ColsClause, from TableClause, clauses ...SelectClause) ([]Values, error) {
-	cb := tx.options.Dialect.NewClauseBuilder()
-
-	cb.WriteString("select ")
-	cols.Write(cb)
-	cb.WriteString(" from ")
-	from.Write(cb)
-	pickAndAppendWhere(cb, clauses)
-	pickAndAppendOrderBy(cb, clauses)
+	cursor, err := tx.query("QueryMany", cols, from, clauses)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
 
-	// TODO: offset, limit, ...
+	result := make([]Values, 0)
+	for cursor.Next() {
+		result = append(result, cursor.Values())
+	}
 
-	query := cb.SQL()
-	if tx.options.LogSQL {
-		log.Printf("QueryMany: '%s'", query)
+	if err := cursor.Err(); err != nil {
+		return nil, err
 	}
 
-	rows, err := tx.tx.QueryContext(tx.ctx, query, cb.Args()...)
+	return result, nil
+}
+
+// QueryIter executes a query that is expected to match any number of rows just like QueryMany does. Instead
+// of loading all rows into memory, QueryIter returns a Cursor which reads the rows one at a time. The
+// caller must close the Cursor when done. The Cursor uses the transaction's connection until it has been
+// closed, so no other statement must be executed on the transaction while the Cursor is open. Some drivers,
+// such as go-sql-driver/mysql, fail with a busy buffer error otherwise.
+func (tx *Tx) QueryIter(cols ColsClause, from TableClause, clauses ...SelectClause) (*Cursor, error) {
+	return tx.query("QueryIter", cols, from, clauses)
+}
+
+// query executes a select query built from the given clauses and returns a Cursor for the resulting rows.
+func (tx *Tx) query(op string, cols ColsClause, from TableClause, clauses []SelectClause) (*Cursor, error) {
+	cb := tx.options.Dialect.NewClauseBuilder()
+	Select(cols, from, clauses...).Write(cb)
+
+	return tx.cursor(op, cb.SQL(), cb.Args(), cols.Names())
+}
+
+// cursor executes query and returns a Cursor for the resulting rows using names as the keys of the values.
+func (tx *Tx) cursor(op, query string, args []interface{}, names []string) (*Cursor, error) {
+	rows, i, err := tx.queryContext(op, query, args)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute '%s': %w", query, err)
 	}
-	defer rows.Close()
 
-	result := make([]Values, 0)
-	for rows.Next() {
-		vals, err := collectValues(cols.Names(), rows)
-		if err != nil {
-			return nil, err
+	return &Cursor{
+		names:        names,
+		query:        query,
+		rows:         rows,
+		dialect:      tx.options.Dialect,
+		interception: i,
+	}, nil
+}
+
+// Cursor iterates over the rows returned from a query. A Cursor is used like sql.Rows: Call Next to advance
+// to the next row and Values to get the row's values. Check Err once Next returned false and always close
+// the Cursor when done.
+type Cursor struct {
+	names        []string
+	types        []string
+	query        string
+	rows         *sql.Rows
+	values       Values
+	err          error
+	count        int64
+	dialect      Dialect
+	interception *interception
+}
+
+// Next advances the Cursor to the next row. It returns false when no more rows are available or an error
+// occured.
+func (c *Cursor) Next() bool {
+	if c.err != nil || !c.rows.Next() {
+		return false
+	}
+
+	if c.types == nil {
+		c.types, c.err = columnTypeNames(c.rows)
+		if c.err != nil {
+			return false
 		}
-		result = append(result, vals)
 	}
 
-	return result, nil
+	c.values, c.err = collectValues(c.names, c.rows)
+	if c.err != nil {
+		return false
+	}
+
+	for idx, name := range c.names {
+		if idx < len(c.types) {
+			c.values[name] = c.dialect.ConvertValue(c.types[idx], c.values[name])
+		}
+	}
+
+	c.count++
+	return true
+}
+
+// Values returns the values of the current row.
+func (c *Cursor) Values() Values {
+	return c.values
+}
+
+// Err returns the error encountered during iteration, if any.
+func (c *Cursor) Err() error {
+	if c.err == nil {
+		if err := c.rows.Err(); err != nil {
+			c.err = c.dialect.TranslateError(err)
+		}
+	}
+
+	if c.err != nil {
+		return fmt.Errorf("failed to execute '%s': %w", c.query, c.err)
+	}
+
+	return nil
+}
+
+// Close closes the Cursor releasing the underlying rows. It is safe to call Close multiple times.
+func (c *Cursor) Close() error {
+	err := c.rows.Close()
+
+	if c.interception != nil {
+		iterErr := c.err
+		if iterErr == nil {
+			if err := c.rows.Err(); err != nil {
+				iterErr = c.dialect.TranslateError(err)
+			}
+		}
+		c.interception.finish(c.count, iterErr)
+		c.interception = nil
+	}
+
+	return err
 }
 
 // QueryCount executes a counting query and returns the number of matching rows.
@@ -162,12 +526,10 @@ func (tx *Tx) QueryCount(from TableClause, where ...WhereClause) (count int, err
 	appendWhere(cb, where)
 
 	query := cb.SQL()
-	if tx.options.LogSQL {
-		log.Printf("QueryCount: '%s'", query)
-	}
 
-	row := tx.tx.QueryRowContext(tx.ctx, query, cb.Args()...)
-	err = row.Scan(&count)
+	err = tx.queryRow("QueryCount", query, cb.Args(), func(row Scanner) error {
+		return row.Scan(&count)
+	})
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -179,88 +541,301 @@ func (tx *Tx) QueryCount(from TableClause, where ...WhereClause) (count int, err
 	return
 }
 
-// Exec executes the given query passing the given args and returns the resulting error or nil.
-// This is just a wrapper for calling ExexContext on the wrapped transaction.
-func (tx *Tx) Exec(query string, args ...interface{}) error {
-	_, err := tx.tx.ExecContext(tx.ctx, query, args...)
-	return err
+// Exec executes the given query passing the given args and returns the result reported by the driver.
+// The query is executed using a cached prepared statement if the statement cache is enabled.
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.exec("Exec", query, args)
 }
 
 // InsertOne inserts a single row.
 func (tx *Tx) InsertOne(into TableClause, values Values) error {
-	cols := make([]string, 0, len(values))
-	colVals := make([]interface{}, 0, len(values))
+	cb := tx.options.Dialect.NewClauseBuilder()
+	WriteInsert(cb, into, values)
 
-	for k, v := range values {
-		cols = append(cols, k)
-		colVals = append(colVals, v)
-	}
+	query := cb.SQL()
+
+	_, err := tx.exec("InsertOne", query, cb.Args())
+	return err
+}
 
+// InsertOneReturning inserts a single row and returns the values of the returning columns generated by
+// the database, such as auto incremented keys or default values. Depending on the Dialect's
+// ReturningStyle the values are either read using a returning or output clause or the last insert id
+// reported by the database. In the latter case returning must name exactly one column which receives the
+// last insert id.
+func (tx *Tx) InsertOneReturning(into TableClause, values Values, returning ColsClause) (Values, error) {
 	cb := tx.options.Dialect.NewClauseBuilder()
+	WriteInsertReturning(cb, into, values, returning)
+
+	if tx.options.Dialect.Returning() != LastInsertID {
+		query := cb.SQL()
+
+		generated, err := tx.first("InsertOneReturning", query, cb.Args(), returning.Names())
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to execute '%s': %w", query, err)
+		}
+		return generated, err
+	}
+
+	names := returning.Names()
+	if len(names) != 1 {
+		return nil, fmt.Errorf("last insert id can only be returned for a single column but got %v", names)
+	}
+
+	query := cb.SQL()
+
+	res, err := tx.exec("InsertOneReturning", query, cb.Args())
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute '%s': %w", query, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert id for '%s': %w", query, err)
+	}
+
+	return Values{names[0]: id}, nil
+}
+
+// WriteInsert writes an insert statement for values into cb. The columns are written in sorted order, so
+// the same set of columns always produces the same statement. It is exported to be used by Dialects
+// rendering statements based on an insert, such as upserts.
+func WriteInsert(cb ClauseWriter, into TableClause, values Values) {
+	writeInsertRows(cb, into, sortedColumns(values), []Values{values})
+}
+
+// WriteInsertReturning writes an insert statement for values into cb which returns the returning columns
+// using the ReturningStyle of cb's Dialect. For LastInsertID a plain insert statement is written.
+func WriteInsertReturning(cb ClauseWriter, into TableClause, values Values, returning ColsClause) {
+	switch cb.Dialect().Returning() {
+	case ReturningClause:
+		WriteInsert(cb, into, values)
+		cb.WriteString(" returning ")
+		returning.Write(cb)
+	case OutputClause:
+		cols := sortedColumns(values)
+		writeInsertColumns(cb, into, cols)
+		cb.WriteString(" output ")
+		for i, name := range returning.Names() {
+			if i > 0 {
+				cb.WriteString(", ")
+			}
+			cb.WriteString("inserted.")
+			WriteIdentifier(cb, name)
+		}
+		writeInsertValues(cb, cols, []Values{values})
+	default:
+		WriteInsert(cb, into, values)
+	}
+}
+
+// InsertMany inserts all rows using multi-row insert statements. All rows must contain the same columns.
+// The rows are split into multiple statements if the number of parameters exceeds the Dialect's
+// MaxParameters or the number of rows exceeds the Dialect's MaxRows.
+func (tx *Tx) InsertMany(into TableClause, rows []Values) error {
+	statements, err := BuildInsertMany(tx.options.Dialect, into, rows)
+	if err != nil {
+		return err
+	}
+
+	for _, cb := range statements {
+		query := cb.SQL()
+
+		if _, err := tx.exec("InsertMany", query, cb.Args()); err != nil {
+			return fmt.Errorf("failed to execute '%s': %w", query, err)
+		}
+	}
+
+	return nil
+}
+
+// BuildInsertMany builds the statements used by InsertMany to insert rows using dialect. The rows are split
+// into chunks respecting the dialect's MaxParameters and MaxRows.
+func BuildInsertMany(dialect Dialect, into TableClause, rows []Values) ([]QueryBuilder, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	cols := sortedColumns(rows[0])
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("failed to insert rows: no columns given")
+	}
+
+	for i, row := range rows[1:] {
+		if !hasColumns(row, cols) {
+			return nil, fmt.Errorf("failed to insert rows: row %d does not match the columns %v of the first row", i+1, cols)
+		}
+	}
+
+	chunkSize := dialect.MaxParameters() / len(cols)
+	if maxRows := dialect.MaxRows(); maxRows > 0 && chunkSize > maxRows {
+		chunkSize = maxRows
+	}
+	if chunkSize < 1 {
+		chunkSize = 1
+	}
 
+	statements := make([]QueryBuilder, 0, (len(rows)+chunkSize-1)/chunkSize)
+	for start := 0; start < len(rows); start += chunkSize {
+		end := start + chunkSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		cb := dialect.NewClauseBuilder()
+		writeInsertRows(cb, into, cols, rows[start:end])
+		statements = append(statements, cb)
+	}
+
+	return statements, nil
+}
+
+// writeInsertRows writes a single insert statement for the values of cols taken from all rows.
+func writeInsertRows(cb ClauseWriter, into TableClause, cols []string, rows []Values) {
+	writeInsertColumns(cb, into, cols)
+	writeInsertValues(cb, cols, rows)
+}
+
+// writeInsertColumns writes the head of an insert statement up to the list of cols.
+func writeInsertColumns(cb ClauseWriter, into TableClause, cols []string) {
 	cb.WriteString("insert into ")
 	into.Write(cb)
 	cb.WriteString(" (")
-
 	for i, col := range cols {
 		if i > 0 {
 			cb.WriteString(", ")
 		}
-		cb.WriteString(col)
+		WriteIdentifier(cb, col)
 	}
+	cb.WriteRune(')')
+}
 
-	cb.WriteString(" ) values (")
+// writeInsertValues writes the values clause of an insert statement containing the values of cols taken
+// from all rows.
+func writeInsertValues(cb ClauseWriter, cols []string, rows []Values) {
+	cb.WriteString(" values ")
 
-	for i, val := range colVals {
+	for i, row := range rows {
 		if i > 0 {
 			cb.WriteString(", ")
 		}
-		cb.BindParameter(val)
+
+		cb.WriteRune('(')
+		for j, col := range cols {
+			if j > 0 {
+				cb.WriteString(", ")
+			}
+			WriteValue(cb, row[col])
+		}
+		cb.WriteRune(')')
+	}
+}
+
+// sortedColumns returns the sorted column names of values.
+func sortedColumns(values Values) []string {
+	cols := make([]string, 0, len(values))
+	for k := range values {
+		cols = append(cols, k)
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+// hasColumns reports whether values contains exactly the columns cols.
+func hasColumns(values Values, cols []string) bool {
+	if len(values) != len(cols) {
+		return false
+	}
+
+	for _, col := range cols {
+		if _, ok := values[col]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Upsert inserts a single row or updates the existing row if the insert conflicts with a row having the
+// same values for conflictCols, which must be covered by a primary key or unique constraint. On conflict
+// the columns named by updateCols are updated with the values given. If updateCols is nil, all columns
+// from values except the conflictCols are updated.
+func (tx *Tx) Upsert(into TableClause, values Values, conflictCols ColsClause, updateCols ColsClause) error {
+	conflict := conflictCols.Names()
+	if len(conflict) == 0 {
+		return fmt.Errorf("upsert requires at least one conflict column")
 	}
 
-	cb.WriteString(")")
+	var update []string
+	if updateCols != nil {
+		update = updateCols.Names()
+	} else {
+		update = upsertUpdateCols(values, conflict)
+	}
+
+	cb := tx.options.Dialect.NewClauseBuilder()
+	tx.options.Dialect.WriteUpsert(cb, into, values, conflict, update)
 
 	query := cb.SQL()
-	if tx.options.LogSQL {
-		log.Printf("InsertOne: '%s'", query)
+
+	if _, err := tx.exec("Upsert", query, cb.Args()); err != nil {
+		return fmt.Errorf("failed to execute '%s': %w", query, err)
+	}
+	return nil
+}
+
+// upsertUpdateCols returns the sorted names of all columns from values not contained in conflict.
+func upsertUpdateCols(values Values, conflict []string) []string {
+	skip := make(map[string]bool, len(conflict))
+	for _, c := range conflict {
+		skip[c] = true
+	}
+
+	update := make([]string, 0, len(values))
+	for k := range values {
+		if !skip[k] {
+			update = append(update, k)
+		}
 	}
 
-	return tx.Exec(query, cb.Args()...)
+	sort.Strings(update)
+	return update
 }
 
-// UpdateMany updates all matching rows with the same values given.
-func (tx *Tx) UpdateMany(table TableClause, values Values, where ...WhereClause) error {
+// UpdateMany updates all matching rows with the same values given. It returns the number of rows affected.
+// Note that MySQL reports the number of rows actually changed unless the clientFoundRows connection option
+// is set.
+func (tx *Tx) UpdateMany(table TableClause, values Values, where ...WhereClause) (int64, error) {
 	cb := tx.options.Dialect.NewClauseBuilder()
+	writeUpdate(cb, table, values, where)
+
+	query := cb.SQL()
+
+	return tx.rowsAffected("UpdateMany", query, cb.Args())
+}
 
+// writeUpdate writes an update statement setting values for all rows matching where. The columns are
+// written in sorted order, so the same set of columns always produces the same statement.
+func writeUpdate(cb ClauseWriter, table TableClause, values Values, where []WhereClause) {
 	cb.WriteString("update ")
 	table.Write(cb)
 	cb.WriteString(" set ")
 
-	first := true
-	for col, val := range values {
-		if first {
-			first = false
-		} else {
+	for i, col := range sortedColumns(values) {
+		if i > 0 {
 			cb.WriteString(", ")
 		}
 
-		cb.WriteString(col)
+		WriteIdentifier(cb, col)
 		cb.WriteString(" = ")
-		cb.BindParameter(val)
+		WriteValue(cb, values[col])
 	}
 
 	appendWhere(cb, where)
-
-	query := cb.SQL()
-	if tx.options.LogSQL {
-		log.Printf("UpdateMany: '%s'", query)
-	}
-
-	return tx.Exec(query, cb.Args()...)
 }
 
-// DeleteMany deletes all matching rows from the database.
-func (tx *Tx) DeleteMany(from TableClause, where ...WhereClause) error {
+// DeleteMany deletes all matching rows from the database. It returns the number of rows deleted.
+func (tx *Tx) DeleteMany(from TableClause, where ...WhereClause) (int64, error) {
 	cb := tx.options.Dialect.NewClauseBuilder()
 
 	cb.WriteString("delete from ")
@@ -268,11 +843,17 @@ func (tx *Tx) DeleteMany(from TableClause, where ...WhereClause) error {
 	appendWhere(cb, where)
 
 	query := cb.SQL()
-	if tx.options.LogSQL {
-		log.Printf("DeleteMany: '%s'", query)
-	}
 
-	return tx.Exec(query, cb.Args()...)
+	return tx.rowsAffected("DeleteMany", query, cb.Args())
+}
+
+// rowsAffected executes query and returns the number of rows affected.
+func (tx *Tx) rowsAffected(op, query string, args []interface{}) (int64, error) {
+	res, err := tx.exec(op, query, args)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
 }
 
 // captureScanner implements the sql package's Scanner interface and captures
@@ -304,6 +885,20 @@ type Scanner interface {
 
 var _ sql.Scanner = &captureScanner{}
 
+// columnTypeNames returns the database type names of the columns returned by rows.
+func columnTypeNames(rows *sql.Rows) ([]string, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = t.DatabaseTypeName()
+	}
+	return names, nil
+}
+
 // collectValues collects the single row values from the given scanner and
 // returns them as a Values value. Names define the column names which must
 // be in the same order as they appeared in the
@@ -341,8 +936,9 @@ func appendWhere(cb ClauseWriter, clauses []WhereClause) {
 	}
 }
 
-// pickAndAppendWhere selects all where clauses from the given clauses writes them to cb.
-func pickAndAppendWhere(cb ClauseWriter, clauses []SelectClause) {
+// pickAndAppendWhere selects all where clauses from the given clauses writes them to cb. If none is set, an
+// always false condition is added so that the query returns no rows.
+func pickAndAppendWhere(cb ClauseWriter, clauses []SelectClause, none bool) {
 	var found bool
 
 	for _, c := range clauses {
@@ -356,20 +952,91 @@ func pickAndAppendWhere(cb ClauseWriter, clauses []SelectClause) {
 			w.Write(cb)
 		}
 	}
+
+	if none {
+		if !found {
+			cb.WriteString(" where ")
+		} else {
+			cb.WriteString(" and ")
+		}
+		cb.WriteString("1 = 0")
+	}
 }
 
-// pickAndAppendOrderBy selects all OrderByClauses and writes them to cb.
-func pickAndAppendOrderBy(cb ClauseWriter, clauses []SelectClause) {
+// pickAndAppendGroupBy selects all GroupByClauses and HavingClauses and writes them to cb.
+func pickAndAppendGroupBy(cb ClauseWriter, clauses []SelectClause) {
+	first := true
+
+	for _, c := range clauses {
+		if g, ok := c.(GroupByClause); ok {
+			if first {
+				cb.WriteString(" group by ")
+				first = false
+			} else {
+				cb.WriteString(", ")
+			}
+			g.Write(cb)
+		}
+	}
+
+	first = true
+
+	for _, c := range clauses {
+		if h, ok := c.(HavingClause); ok {
+			if first {
+				cb.WriteString(" having ")
+				first = false
+			} else {
+				cb.WriteString(" and ")
+			}
+			h.Write(cb)
+		}
+	}
+}
+
+// pickAndAppendOrderBy selects all OrderByClauses and writes them to cb. It returns whether an order by
+// clause has been written.
+func pickAndAppendOrderBy(cb ClauseWriter, clauses []SelectClause) bool {
 	first := true
 
 	for _, c := range clauses {
 		if w, ok := c.(OrderByClause); ok {
 			if first {
 				cb.WriteString(" order by ")
+				first = false
 			} else {
 				cb.WriteString(", ")
 			}
 			w.Write(cb)
 		}
 	}
+
+	return !first
+}
+
+// pickLimitOffset selects the last LimitClause and OffsetClause. It returns a limit of -1 if no
+// LimitClause is given.
+func pickLimitOffset(clauses []SelectClause) (limit, offset int) {
+	limit = -1
+
+	for _, c := range clauses {
+		switch l := c.(type) {
+		case LimitClause:
+			limit = l.Limit()
+		case OffsetClause:
+			offset = l.Offset()
+		}
+	}
+
+	return
+}
+
+// appendLimitOffset lets the writer's dialect write limit and offset to cb. Nothing is written for a
+// limit of 0 as not all databases support it; pickAndAppendWhere is used to return no rows instead.
+func appendLimitOffset(cb ClauseWriter, limit, offset int, ordered bool) {
+	if limit == 0 || (limit < 0 && offset == 0) {
+		return
+	}
+
+	cb.Dialect().WriteLimitOffset(cb, limit, offset, ordered)
 }
@@ -0,0 +1,58 @@
+// Copyright 2021 Alexander Metzner.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWriteInsert(t *testing.T) {
+	values := Values{"text": "hello", "id": "1", "attachment": nil}
+
+	// Run multiple times as map iteration order is randomized.
+	for i := 0; i < 10; i++ {
+		cb := NewDefaultClauseBuilder(&DefaultDialect{})
+		WriteInsert(cb, Into("messages"), values)
+
+		expected := `insert into "messages" ("attachment", "id", "text") values (?, ?, ?)`
+		if cb.SQL() != expected {
+			t.Fatalf("expected\n%s\nbut got\n%s", expected, cb.SQL())
+		}
+
+		if !reflect.DeepEqual(cb.Args(), []interface{}{nil, "1", "hello"}) {
+			t.Fatalf("got unexpected args: %v", cb.Args())
+		}
+	}
+}
+
+func TestWriteUpdate(t *testing.T) {
+	values := Values{"text": "hello", "attachment": nil}
+
+	// Run multiple times as map iteration order is randomized.
+	for i := 0; i < 10; i++ {
+		cb := NewDefaultClauseBuilder(&DefaultDialect{})
+		writeUpdate(cb, Table("messages"), values, []WhereClause{Where(Eq("id", "1"))})
+
+		expected := `update "messages" set "attachment" = ?, "text" = ? where ("id" = ?)`
+		if cb.SQL() != expected {
+			t.Fatalf("expected\n%s\nbut got\n%s", expected, cb.SQL())
+		}
+
+		if !reflect.DeepEqual(cb.Args(), []interface{}{nil, "hello", "1"}) {
+			t.Fatalf("got unexpected args: %v", cb.Args())
+		}
+	}
+}